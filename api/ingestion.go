@@ -0,0 +1,73 @@
+// api/ingestion.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListIngestionRuns returns the newest ingestion runs, optionally filtered by
+// source, account_id, status (succeeded|failed), since (RFC3339) and limit
+func ListIngestionRuns(c *gin.Context) {
+	filter := storage.RunFilter{
+		Source:    c.Query("source"),
+		AccountID: c.Query("account_id"),
+		Status:    c.Query("status"),
+	}
+	if filter.Status != "" && filter.Status != "succeeded" && filter.Status != "failed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be succeeded or failed"})
+		return
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	runs, err := storage.ListIngestionRuns(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+		return
+	}
+	if runs == nil {
+		runs = []models.IngestionRun{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// GetSourceSyncStatus returns the last successful sync per source and account,
+// with any failure since then; /ingestion/sources/:source narrows it to one source
+func GetSourceSyncStatus(c *gin.Context) {
+	source := c.Param("source")
+
+	syncs, err := storage.LastSuccessfulSyncs(c.Request.Context(), source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+		return
+	}
+	if source != "" && len(syncs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No successful sync recorded for source"})
+		return
+	}
+	if syncs == nil {
+		syncs = []models.SourceSync{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": syncs})
+}
@@ -0,0 +1,31 @@
+// api/metrics.go
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"campaign-analytics/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMetricDefinitions returns the custom metrics that can be requested with metrics=
+func ListMetricDefinitions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": metrics.Custom.Definitions()})
+}
+
+// customMetricsParam reads the comma-separated metrics= query parameter. It
+// writes a 400 response and returns ok=false if a name is not registered.
+func customMetricsParam(c *gin.Context) (names []string, ok bool) {
+	raw := c.Query("metrics")
+	if raw == "" {
+		return nil, true
+	}
+	names, err := metrics.Custom.Resolve(strings.Split(raw, ","))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + "; see GET /metrics"})
+		return nil, false
+	}
+	return names, true
+}
@@ -0,0 +1,141 @@
+// api/rollups.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"campaign-analytics/fx"
+	"campaign-analytics/metrics"
+	"campaign-analytics/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rollupRow is one group of a rollup: its dimension values and summed metrics
+type rollupRow struct {
+	Group map[string]string `json:"group"`
+	metrics.Report
+	Metrics metrics.Values `json:"metrics,omitempty"`
+}
+
+// GetRollups returns metrics summed across campaigns and grouped by one or more
+// of platform, campaign, account and day, between from and to (inclusive days,
+// YYYY-MM-DD). Groups can be filtered and ordered, e.g. the top 10 campaigns by
+// spend with group_by=campaign&order_by=cost&limit=10.
+func GetRollups(c *gin.Context) {
+	custom, ok := customMetricsParam(c)
+	if !ok {
+		return
+	}
+	loc, ok := timezoneParam(c)
+	if !ok {
+		return
+	}
+	from, to, ok := dayRangeParams(c, loc, defaultRangeDays)
+	if !ok {
+		return
+	}
+
+	q := storage.RollupQuery{
+		GroupBy:  strings.Split(c.DefaultQuery("group_by", "platform"), ","),
+		Filters:  make(map[string][]string),
+		Location: loc,
+		From:     from,
+		To:       to,
+		OrderBy:  c.Query("order_by"),
+	}
+	// filter=platform:Google,platform:Meta keeps either platform; different
+	// dimensions must all match
+	if raw := c.Query("filter"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			dim, value, found := strings.Cut(pair, ":")
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "filter must be dimension:value pairs, e.g. platform:Google"})
+				return
+			}
+			q.Filters[dim] = append(q.Filters[dim], value)
+		}
+	}
+	if platform := c.Query("platform"); platform != "" {
+		q.Filters["platform"] = append(q.Filters["platform"], platform)
+	}
+	if accountID := c.Query("account_id"); accountID != "" {
+		q.Filters["account"] = append(q.Filters["account"], accountID)
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+		q.Desc = true
+	case "asc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		q.Limit = n
+	}
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("rollups:%s:%s:%s:%s:%s:%s:%t:%d", strings.Join(q.GroupBy, ","), rollupFilterKey(q.Filters),
+		loc, from.Format("2006-01-02"), to.Format("2006-01-02"), q.OrderBy, q.Desc, q.Limit)
+
+	ctx := c.Request.Context()
+	var rows []rollupRow
+	cached := false
+	if data, err := storage.GetCache(ctx, cacheKey); err == nil && data != "" {
+		cached = json.Unmarshal([]byte(data), &rows) == nil
+	}
+	if !cached {
+		groups, err := storage.Rollup(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+			return
+		}
+		rows = make([]rollupRow, len(groups))
+		for i, g := range groups {
+			rows[i] = rollupRow{Group: g.Group, Report: metrics.NewReport(g.Totals)}
+		}
+		serialized, _ := json.Marshal(rows)
+		storage.SetCache(ctx, cacheKey, string(serialized), 30*time.Second)
+	}
+
+	if len(custom) > 0 {
+		for i := range rows {
+			rows[i].Metrics = metrics.Custom.Evaluate(rows[i].Totals, custom)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     rows,
+		"group_by": q.GroupBy,
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"currency": fx.ReportingCurrency,
+		"cached":   cached,
+	})
+}
+
+// rollupFilterKey renders filters in a fixed order for the cache key
+func rollupFilterKey(filters map[string][]string) string {
+	pairs := make([]string, 0, len(filters))
+	for dim, values := range filters {
+		sorted := append([]string(nil), values...)
+		sort.Strings(sorted)
+		pairs = append(pairs, dim+"="+strings.Join(sorted, "|"))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
@@ -4,12 +4,15 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"campaign-analytics/fx"
+	"campaign-analytics/metrics"
 	"campaign-analytics/models"
 	"campaign-analytics/storage"
 
@@ -29,60 +32,70 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// GetCampaignInsights returns the latest metrics for a campaign from cache or DB
+// insightRow is one stored row with the metrics derived from it
+type insightRow struct {
+	models.CampaignMetrics
+	metrics.Derived
+}
+
+// insights is the cached part of the GetCampaignInsights response
+type insights struct {
+	Data   insightRow     `json:"data"`
+	Totals metrics.Report `json:"totals"`
+}
+
+// GetCampaignInsights returns the latest metrics for a campaign from cache or DB,
+// along with totals over every row matching the filters. from and to select
+// reporting days (YYYY-MM-DD, inclusive), as in /campaign/:id/summary. Derived
+// metrics in totals are computed from the summed components, as are the custom
+// metrics named in metrics=. Money is in the reporting currency named by "currency".
 func GetCampaignInsights(c *gin.Context) {
 	campaignID := c.Param("id")
-	from := c.Query("from")
-	to := c.Query("to")
-	platform := c.Query("platform")
+	custom, ok := customMetricsParam(c)
+	if !ok {
+		return
+	}
+	loc, ok := timezoneParam(c)
+	if !ok {
+		return
+	}
+	// Either end of the range may be left open
+	var days [2]time.Time // from, to
+	for i, name := range []string{"from", "to"} {
+		if s := c.Query(name); s != "" {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a date (YYYY-MM-DD)"})
+				return
+			}
+			days[i] = d
+		}
+	}
+	from, to := days[0], days[1]
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
 
-	cacheKey := fmt.Sprintf("campaign:%s:insights:%s:%s:%s", campaignID, from, to, platform)
+	filter := storage.MetricFilter{
+		CampaignID: campaignID,
+		Platform:   c.Query("platform"),
+		AccountID:  c.Query("account_id"),
+	}
+	cacheKey := fmt.Sprintf("campaign:%s:insights:%s:%s:%s:%s:%s", campaignID, loc,
+		c.Query("from"), c.Query("to"), filter.Platform, filter.AccountID)
 
-	cached, err := storage.GetCache(cacheKey)
+	ctx := c.Request.Context()
+	cached, err := storage.GetCache(ctx, cacheKey)
 	if err == nil && cached != "" {
-		var response models.CampaignMetrics
+		var response insights
 		if err := json.Unmarshal([]byte(cached), &response); err == nil {
-			c.JSON(http.StatusOK, gin.H{"data": response, "cached": true})
+			respondInsights(c, response, custom, true)
 			return
 		}
 	}
 
-	query := `SELECT campaign_id, platform, impressions, clicks, conversions, cost, revenue, timestamp
-			FROM campaign_metrics WHERE campaign_id = $1`
-	args := []interface{}{campaignID}
-	argIdx := 2
-
-	if from != "" {
-		query += fmt.Sprintf(" AND timestamp >= $%d", argIdx)
-		args = append(args, from)
-		argIdx++
-	}
-	if to != "" {
-		query += fmt.Sprintf(" AND timestamp <= $%d", argIdx)
-		args = append(args, to)
-		argIdx++
-	}
-	if platform != "" {
-		query += fmt.Sprintf(" AND platform = $%d", argIdx)
-		args = append(args, platform)
-		argIdx++
-	}
-
-	query += " ORDER BY timestamp DESC LIMIT 1"
-
-	row := storage.DB.QueryRow(query, args...)
-	var result models.CampaignMetrics
-	err = row.Scan(
-		&result.CampaignID,
-		&result.Platform,
-		&result.Impressions,
-		&result.Clicks,
-		&result.Conversions,
-		&result.Cost,
-		&result.Revenue,
-		&result.Timestamp,
-	)
-
+	result, err := storage.LatestMetric(ctx, filter, loc, from, to)
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No data found for campaign"})
 		return
@@ -91,10 +104,31 @@ func GetCampaignInsights(c *gin.Context) {
 		return
 	}
 
-	serialized, _ := json.Marshal(result)
-	storage.SetCache(cacheKey, string(serialized), 30*time.Second)
+	// Sums are exact NUMERIC in Postgres, so ratios come from exact totals
+	totals, err := storage.MetricTotals(ctx, filter, loc, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+		return
+	}
+
+	response := insights{
+		Data:   insightRow{result, metrics.Compute(metrics.Of(result))},
+		Totals: metrics.NewReport(totals),
+	}
+	serialized, _ := json.Marshal(response)
+	storage.SetCache(ctx, cacheKey, string(serialized), 30*time.Second)
 
-	c.JSON(http.StatusOK, gin.H{"data": result, "cached": false})
+	respondInsights(c, response, custom, false)
+}
+
+// respondInsights writes an insights response, evaluating the custom metrics
+// over its totals
+func respondInsights(c *gin.Context, response insights, custom []string, cached bool) {
+	body := gin.H{"data": response.Data, "totals": response.Totals, "currency": fx.ReportingCurrency, "cached": cached}
+	if len(custom) > 0 {
+		body["metrics"] = metrics.Custom.Evaluate(response.Totals.Totals, custom)
+	}
+	c.JSON(http.StatusOK, body)
 }
 
 // InitRouter sets up the Gin router and routes
@@ -103,6 +137,14 @@ func InitRouter() *gin.Engine {
 
 	r.Use(AuthMiddleware())
 	r.GET("/campaign/:id/insights", GetCampaignInsights)
+	r.GET("/campaign/:id/timeseries", GetCampaignTimeseries)
+	r.GET("/campaign/:id/summary", GetCampaignSummary)
+	r.GET("/rollups", GetRollups)
+	r.GET("/metrics", ListMetricDefinitions)
+	r.GET("/ingestion/runs", ListIngestionRuns)
+	r.GET("/ingestion/sources", GetSourceSyncStatus)
+	r.GET("/ingestion/sources/:source", GetSourceSyncStatus)
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
 	return r
 }
@@ -0,0 +1,94 @@
+// api/summary.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"campaign-analytics/fx"
+	"campaign-analytics/metrics"
+	"campaign-analytics/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// periodSummary is a campaign's totals over one date range
+type periodSummary struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	metrics.Report
+	Metrics metrics.Values `json:"metrics,omitempty"`
+}
+
+// GetCampaignSummary returns a campaign's totals between from and to (inclusive
+// days, YYYY-MM-DD), with ratios recomputed from the totals, compared to the
+// preceding period of the same number of days
+func GetCampaignSummary(c *gin.Context) {
+	campaignID := c.Param("id")
+	custom, ok := customMetricsParam(c)
+	if !ok {
+		return
+	}
+	loc, ok := timezoneParam(c)
+	if !ok {
+		return
+	}
+	from, to, ok := dayRangeParams(c, loc, defaultRangeDays)
+	if !ok {
+		return
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	prevTo := from.AddDate(0, 0, -1)
+	prevFrom := prevTo.AddDate(0, 0, -(days - 1))
+
+	filter := storage.MetricFilter{
+		CampaignID: campaignID,
+		Platform:   c.Query("platform"),
+		AccountID:  c.Query("account_id"),
+	}
+	cacheKey := fmt.Sprintf("campaign:%s:summary:%s:%s:%s:%s:%s", campaignID, loc,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), filter.Platform, filter.AccountID)
+
+	ctx := c.Request.Context()
+	var periods [2]periodSummary // current, previous
+	cached := false
+	if data, err := storage.GetCache(ctx, cacheKey); err == nil && data != "" {
+		cached = json.Unmarshal([]byte(data), &periods) == nil
+	}
+	if !cached {
+		for i, r := range [][2]time.Time{{from, to}, {prevFrom, prevTo}} {
+			totals, err := storage.MetricTotals(ctx, filter, loc, r[0], r[1])
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+				return
+			}
+			periods[i] = periodSummary{
+				From:   r[0].Format("2006-01-02"),
+				To:     r[1].Format("2006-01-02"),
+				Report: metrics.NewReport(totals),
+			}
+		}
+		serialized, _ := json.Marshal(periods)
+		storage.SetCache(ctx, cacheKey, string(serialized), 30*time.Second)
+	}
+
+	current, previous := periods[0], periods[1]
+	curValues, prevValues := current.Values(), previous.Values()
+	if len(custom) > 0 {
+		current.Metrics = metrics.Custom.Evaluate(current.Totals, custom)
+		previous.Metrics = metrics.Custom.Evaluate(previous.Totals, custom)
+		for _, name := range custom {
+			curValues[name], prevValues[name] = current.Metrics[name], previous.Metrics[name]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     current,
+		"previous": previous,
+		"change":   metrics.Compare(curValues, prevValues),
+		"currency": fx.ReportingCurrency,
+		"cached":   cached,
+	})
+}
@@ -0,0 +1,147 @@
+// api/timeseries.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"campaign-analytics/fx"
+	"campaign-analytics/metrics"
+	"campaign-analytics/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRangeDays is how many days a range covers when from is not given
+const defaultRangeDays = 30
+
+// seriesBucket is one point of a time series: the bucket's totals with their
+// derived metrics, plus any custom metrics requested
+type seriesBucket struct {
+	Start string `json:"start"`
+	metrics.Report
+	Metrics metrics.Values `json:"metrics,omitempty"`
+}
+
+// GetCampaignTimeseries returns a campaign's metrics summed per hour, day, week
+// or month between from and to (inclusive days, YYYY-MM-DD) in the timezone tz.
+// Every bucket in the range is returned, with zeros where there is no data.
+func GetCampaignTimeseries(c *gin.Context) {
+	campaignID := c.Param("id")
+	custom, ok := customMetricsParam(c)
+	if !ok {
+		return
+	}
+
+	interval, err := storage.ParseInterval(c.DefaultQuery("interval", "day"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, ok := timezoneParam(c)
+	if !ok {
+		return
+	}
+	from, to, ok := dayRangeParams(c, loc, defaultRangeDays)
+	if !ok {
+		return
+	}
+
+	q := storage.SeriesQuery{
+		MetricFilter: storage.MetricFilter{
+			CampaignID: campaignID,
+			Platform:   c.Query("platform"),
+			AccountID:  c.Query("account_id"),
+		},
+		Interval: interval,
+		Location: loc,
+		From:     from,
+		To:       to,
+	}
+	if _, err := q.Buckets(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cacheKey := fmt.Sprintf("campaign:%s:timeseries:%s:%s:%s:%s:%s:%s", campaignID, interval, loc,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), q.Platform, q.AccountID)
+
+	ctx := c.Request.Context()
+	var buckets []seriesBucket
+	cached := false
+	if data, err := storage.GetCache(ctx, cacheKey); err == nil && data != "" {
+		cached = json.Unmarshal([]byte(data), &buckets) == nil
+	}
+	if !cached {
+		points, err := storage.MetricSeries(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query DB"})
+			return
+		}
+		buckets = make([]seriesBucket, len(points))
+		for i, p := range points {
+			buckets[i] = seriesBucket{Start: p.Start.Format(time.RFC3339), Report: metrics.NewReport(p.Totals)}
+		}
+		serialized, _ := json.Marshal(buckets)
+		storage.SetCache(ctx, cacheKey, string(serialized), 30*time.Second)
+	}
+
+	if len(custom) > 0 {
+		for i := range buckets {
+			buckets[i].Metrics = metrics.Custom.Evaluate(buckets[i].Totals, custom)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     buckets,
+		"interval": interval,
+		"tz":       loc.String(),
+		"from":     from.Format("2006-01-02"),
+		"to":       to.Format("2006-01-02"),
+		"currency": fx.ReportingCurrency,
+		"cached":   cached,
+	})
+}
+
+// timezoneParam reads the tz query parameter, defaulting to UTC. It writes a
+// 400 response and returns ok=false if tz is not an IANA timezone.
+func timezoneParam(c *gin.Context) (loc *time.Location, ok bool) {
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil || loc == time.Local {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA timezone such as America/New_York"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// dayRangeParams reads the from and to query parameters as days (YYYY-MM-DD).
+// to defaults to today in loc and from to the days-long range ending at to.
+// It writes a 400 response and returns ok=false if either is invalid.
+func dayRangeParams(c *gin.Context, loc *time.Location, days int) (from, to time.Time, ok bool) {
+	now := time.Now().In(loc)
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD)"})
+			return from, to, false
+		}
+		to = t
+	}
+	from = to.AddDate(0, 0, -(days - 1))
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD)"})
+			return from, to, false
+		}
+		from = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return from, to, false
+	}
+	return from, to, true
+}
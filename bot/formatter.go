@@ -1,29 +1,58 @@
 package bot
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
+// summaryIntents are the figures a "summary" answer combines, with the
+// totals key each one reads
+var summaryIntents = []struct{ intent, key string }{
+	{"Spend", "cost"},
+	{"ROAS", "roas"},
+	{"CTR", "ctr"},
+}
+
+// FormatResponse answers intent from an insights API response. Figures come
+// from its "totals", which cover every row of the campaign; ratios there are
+// null when undefined (e.g. ROAS with no spend).
 func FormatResponse(intent string, data map[string]interface{}) string {
+	totals, _ := data["totals"].(map[string]interface{})
+	currency, _ := data["currency"].(string)
+
 	switch intent {
 	case "ROAS":
-		roas, ok := data["roas"].(float64)
+		roas, ok := totals["roas"].(float64)
 		if !ok {
 			return "Sorry, ROAS data is not available for your campaign."
 		}
 		return fmt.Sprintf("The ROAS for your campaign is %.2f.", roas)
 
 	case "CTR":
-		ctr, ok := data["ctr"].(float64)
+		ctr, ok := totals["ctr"].(float64)
 		if !ok {
 			return "Sorry, CTR data is not available for your campaign."
 		}
 		return fmt.Sprintf("The CTR for your campaign is %.2f%%.", ctr*100)
 
 	case "Spend":
-		spend, ok := data["spend"].(float64)
+		spend, ok := totals["cost"].(float64)
 		if !ok {
 			return "Sorry, Spend data is not available."
 		}
-		return fmt.Sprintf("You spent $%.2f on your campaigns.", spend)
+		return fmt.Sprintf("You spent %.2f %s on your campaigns.", spend, currency)
+
+	case "summary":
+		var parts []string
+		for _, s := range summaryIntents {
+			if _, ok := totals[s.key].(float64); ok {
+				parts = append(parts, FormatResponse(s.intent, data))
+			}
+		}
+		if len(parts) == 0 {
+			return "Sorry, no data is available for your campaign yet."
+		}
+		return strings.Join(parts, " ")
 
 	default:
 		return "Sorry, I didn't understand your request."
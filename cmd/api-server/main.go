@@ -2,44 +2,107 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"campaign-analytics/api"
+	"campaign-analytics/config"
+	"campaign-analytics/fx"
 	"campaign-analytics/ingestion"
+	"campaign-analytics/metrics"
+	"campaign-analytics/processor"
 	"campaign-analytics/storage"
 )
 
 func main() {
+	// Cancelled on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Load exchange rates for normalizing ingested amounts
+	if err := fx.LoadDefault(); err != nil {
+		fmt.Println("[ERROR] Failed to load FX rates:", err)
+		os.Exit(1)
+	}
+
+	// Load custom metric definitions served by the API
+	if err := metrics.LoadDefault(); err != nil {
+		fmt.Println("[ERROR] Failed to load metric definitions:", err)
+		os.Exit(1)
+	}
+
 	// Initialize Postgres
-	if err := storage.InitDB(); err != nil {
+	if err := storage.InitDB(ctx); err != nil {
 		fmt.Println("[ERROR] Failed to connect to DB:", err)
 		os.Exit(1)
 	}
+	defer storage.DB.Close()
 
 	// Initialize Redis
-	if err := storage.InitRedis(); err != nil {
+	if err := storage.InitRedis(ctx); err != nil {
 		fmt.Println("[ERROR] Failed to connect to Redis:", err)
 		os.Exit(1)
 	}
 	fmt.Println("[INFO] Connected to Redis")
 
+	// Start the processing pipeline between ingestion and storage
+	pipeline := processor.NewPipeline(processor.PipelineConfigFromEnv())
+	pipeline.Start(ctx)
+	processor.Default = pipeline
+
 	// Decide ingestion mode
+	ingestionDone := make(chan struct{})
 	mode := os.Getenv("DATA_SOURCE")
 	if mode == "real" {
 		fmt.Println("[BOOT] Running in REAL ingestion mode (Meta, Google, TikTok, LinkedIn)")
-		go ingestion.StartRealFetcher()
+		go func() {
+			defer close(ingestionDone)
+			ingestion.StartRealFetcher(ctx)
+		}()
 	} else {
 		fmt.Println("[BOOT] Running in FAKE data simulation mode")
-		go ingestion.StartSimulator()
+		go func() {
+			defer close(ingestionDone)
+			ingestion.StartSimulator(ctx)
+		}()
 	}
 
 	// Small delay to ensure ingestion is warmed up
 	time.Sleep(1 * time.Second)
 
 	// Start API server
-	r := api.InitRouter()
-	fmt.Println("[INFO] API Server started at http://localhost:8080")
-	r.Run(":8080")
+	srv := &http.Server{Addr: ":8080", Handler: api.InitRouter()}
+	go func() {
+		fmt.Println("[INFO] API Server started at http://localhost:8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("[ERROR] API server failed:", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	fmt.Println("[INFO] Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout())
+	defer cancel()
+
+	// Drain in-flight API requests, then let fetchers finish their current batch
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fmt.Println("[ERROR] API server shutdown:", err)
+	}
+	select {
+	case <-ingestionDone:
+		fmt.Println("[INFO] Ingestion stopped cleanly")
+	case <-shutdownCtx.Done():
+		fmt.Println("[ERROR] Timed out waiting for ingestion to stop")
+	}
+	if err := pipeline.Close(shutdownCtx); err != nil {
+		fmt.Println("[ERROR]", err)
+	}
 }
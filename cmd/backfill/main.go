@@ -0,0 +1,108 @@
+// main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"campaign-analytics/fx"
+	"campaign-analytics/ingestion"
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+func main() {
+	source := flag.String("source", "", "source to backfill (meta, google, tiktok, linkedin)")
+	account := flag.String("account", "", "account ID to backfill (required if the source has several accounts)")
+	from := flag.String("from", "", "first day to fetch (YYYY-MM-DD)")
+	to := flag.String("to", "", "last day to fetch (YYYY-MM-DD), defaults to yesterday")
+	chunkDays := flag.Int("chunk-days", 7, "number of days requested per API call")
+	checkpoint := flag.String("checkpoint", "", "checkpoint file (default backfill-<source>-<account>.json)")
+	flag.Parse()
+
+	if *source == "" || *from == "" {
+		fmt.Println("[ERROR] --source and --from are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fromDay, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		fmt.Println("[ERROR] Invalid --from date:", err)
+		os.Exit(2)
+	}
+	toDay := time.Now().UTC().AddDate(0, 0, -1)
+	if *to != "" {
+		if toDay, err = time.Parse("2006-01-02", *to); err != nil {
+			fmt.Println("[ERROR] Invalid --to date:", err)
+			os.Exit(2)
+		}
+	}
+
+	if err := fx.LoadDefault(); err != nil {
+		fmt.Println("[ERROR] Failed to load FX rates:", err)
+		os.Exit(2)
+	}
+	accounts, err := ingestion.LoadAccounts(os.Getenv("INGESTION_ACCOUNTS_FILE"))
+	if err != nil {
+		fmt.Println("[ERROR]", err)
+		os.Exit(2)
+	}
+	acct, err := pickAccount(accounts, *source, *account)
+	if err != nil {
+		fmt.Println("[ERROR]", err)
+		os.Exit(2)
+	}
+	src, err := ingestion.NewSource(acct)
+	if err != nil {
+		fmt.Println("[ERROR]", err)
+		os.Exit(2)
+	}
+
+	if *checkpoint == "" {
+		*checkpoint = fmt.Sprintf("backfill-%s-%s.json", src.Name(), acct.AccountID)
+	}
+
+	// Ctrl-C stops between chunks (rows already fetched are still stored); re-run to resume
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := storage.InitDB(ctx); err != nil {
+		fmt.Println("[ERROR] Failed to connect to DB:", err)
+		os.Exit(1)
+	}
+
+	store := func(metrics []models.CampaignMetrics) ([]storage.WriteResult, error) {
+		return storage.InsertCampaignMetricsBatch(context.WithoutCancel(ctx), metrics)
+	}
+
+	err = ingestion.Backfill(ctx, src, acct.AccountID, fromDay, toDay, *chunkDays, *checkpoint, store)
+	if err != nil {
+		fmt.Println("[ERROR] Backfill failed:", err)
+		fmt.Printf("[INFO] Re-run the same command to resume from %s\n", *checkpoint)
+		os.Exit(1)
+	}
+}
+
+// pickAccount finds the account to backfill for a platform
+func pickAccount(accounts []ingestion.Account, platform, accountID string) (ingestion.Account, error) {
+	var matches []ingestion.Account
+	for _, acct := range accounts {
+		if strings.EqualFold(acct.Platform, platform) && (accountID == "" || acct.AccountID == accountID) {
+			matches = append(matches, acct)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return ingestion.Account{}, fmt.Errorf("no %s account %q configured", platform, accountID)
+	case len(matches) > 1:
+		return ingestion.Account{}, fmt.Errorf("%d %s accounts configured, pick one with --account", len(matches), platform)
+	}
+	return matches[0], nil
+}
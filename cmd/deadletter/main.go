@@ -0,0 +1,182 @@
+// main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/processor"
+	"campaign-analytics/storage"
+)
+
+const usage = `usage: deadletter <command> [flags]
+
+Commands:
+  list     show dead-lettered metrics
+  replay   write dead-lettered metrics to the database again
+  purge    delete dead-lettered metrics without writing them
+
+Run "deadletter <command> -h" for the flags of a command.
+The file is DEAD_LETTER_FILE (default dead-letter.jsonl) unless --file is given.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	file := fs.String("file", processor.DeadLetterPath, "dead-letter file")
+	ids := fs.String("id", "", "comma-separated entry IDs to act on (default all)")
+	campaign := fs.String("campaign", "", "only entries for this campaign ID")
+	before := fs.String("before", "", "only entries that failed before this RFC3339 time")
+
+	var err error
+	switch cmd {
+	case "list":
+		fs.Parse(args)
+		err = list(*file, newFilter(*ids, *campaign, *before))
+	case "replay":
+		fs.Parse(args)
+		err = replay(*file, newFilter(*ids, *campaign, *before))
+	case "purge":
+		all := fs.Bool("all", false, "required when no other filter is given")
+		fs.Parse(args)
+		if *ids == "" && *campaign == "" && *before == "" && !*all {
+			fmt.Println("[ERROR] purge needs --id, --campaign, --before or --all")
+			os.Exit(2)
+		}
+		err = purge(*file, newFilter(*ids, *campaign, *before))
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println("[ERROR]", err)
+		os.Exit(1)
+	}
+}
+
+// filter selects dead-letter entries; empty fields match everything
+type filter struct {
+	ids      map[string]bool
+	campaign string
+	before   time.Time
+}
+
+func newFilter(ids, campaign, before string) filter {
+	f := filter{campaign: campaign}
+	if ids != "" {
+		f.ids = make(map[string]bool)
+		for _, id := range strings.Split(ids, ",") {
+			f.ids[strings.TrimSpace(id)] = true
+		}
+	}
+	if before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			fmt.Println("[ERROR] Invalid --before time:", err)
+			os.Exit(2)
+		}
+		f.before = t
+	}
+	return f
+}
+
+func (f filter) match(e processor.DeadLetter) bool {
+	return (f.ids == nil || f.ids[e.ID]) &&
+		(f.campaign == "" || e.Metric.CampaignID == f.campaign) &&
+		(f.before.IsZero() || e.FailedAt.Before(f.before))
+}
+
+// split separates the entries f matches from the rest
+func (f filter) split(entries []processor.DeadLetter) (matched, rest []processor.DeadLetter) {
+	for _, e := range entries {
+		if f.match(e) {
+			matched = append(matched, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	return matched, rest
+}
+
+// list prints the matching entries, one per line
+func list(file string, f filter) error {
+	entries, err := processor.ReadDeadLetters(file)
+	if err != nil {
+		return err
+	}
+	matched, _ := f.split(entries)
+	for _, e := range matched {
+		fmt.Printf("%s  %s  attempts=%d  %s/%s @ %s  error: %s\n",
+			e.ID, e.FailedAt.Format(time.RFC3339), e.Attempts, e.Metric.Platform, e.Metric.CampaignID, e.Metric.Timestamp.Format(time.RFC3339), e.Error)
+	}
+	fmt.Printf("%d of %d entries\n", len(matched), len(entries))
+	return nil
+}
+
+// replay writes the matching entries again. Entries that still fail stay in
+// the file with their attempt count and error updated.
+func replay(file string, f filter) error {
+	ctx := context.Background()
+	if err := storage.InitDB(ctx); err != nil {
+		return fmt.Errorf("connect to DB: %w", err)
+	}
+
+	entries, claimed, err := processor.ClaimDeadLetters(file)
+	if err != nil {
+		return err
+	}
+	matched, keep := f.split(entries)
+
+	metrics := make([]models.CampaignMetrics, len(matched))
+	for i, e := range matched {
+		metrics[i] = e.Metric
+	}
+
+	// Try one batch first; if it fails, go row by row so one bad row
+	// doesn't hold back the rest
+	replayed := 0
+	if _, err := storage.InsertCampaignMetricsBatch(ctx, metrics); err == nil {
+		replayed = len(matched)
+	} else {
+		fmt.Printf("[INFO] Batch replay failed (%v), retrying row by row\n", err)
+		for _, e := range matched {
+			if _, err := storage.InsertCampaignMetrics(ctx, e.Metric); err != nil {
+				e.Attempts++
+				e.Error = err.Error()
+				e.FailedAt = time.Now().UTC()
+				keep = append(keep, e)
+				continue
+			}
+			replayed++
+		}
+	}
+
+	if err := processor.ReleaseDeadLetters(file, claimed, keep); err != nil {
+		return err
+	}
+	fmt.Printf("Replayed %d of %d matching entries, %d left in %s\n", replayed, len(matched), len(keep), file)
+	return nil
+}
+
+// purge removes the matching entries from the file
+func purge(file string, f filter) error {
+	entries, claimed, err := processor.ClaimDeadLetters(file)
+	if err != nil {
+		return err
+	}
+	matched, keep := f.split(entries)
+	if err := processor.ReleaseDeadLetters(file, claimed, keep); err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d entries, %d left in %s\n", len(matched), len(keep), file)
+	return nil
+}
@@ -0,0 +1,42 @@
+// config/env.go
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Int reads an integer env var, falling back to def when it is unset, not a
+// number or below min. Pass min 0 for knobs where 0 turns a feature off.
+func Int(key string, def, min int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n < min {
+		return def
+	}
+	return n
+}
+
+// String reads an env var, falling back to def when unset
+func String(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// Duration reads a duration env var (e.g. "30s"), falling back to def when it
+// is unset, invalid or not positive
+func Duration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// ShutdownTimeout bounds how long in-flight requests and fetches may take to
+// drain: SHUTDOWN_TIMEOUT, default 30s
+func ShutdownTimeout() time.Duration {
+	return Duration("SHUTDOWN_TIMEOUT", 30*time.Second)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInt(t *testing.T) {
+	cases := []struct {
+		value    string
+		min, def int
+		want     int
+	}{
+		{"", 1, 4, 4},
+		{"8", 1, 4, 8},
+		{"abc", 1, 4, 4},
+		{"0", 1, 4, 4},
+		// A knob that allows 0 keeps it instead of falling back
+		{"0", 0, 4, 0},
+		{"-1", 0, 4, 4},
+	}
+	for _, c := range cases {
+		t.Setenv("CONFIG_TEST_INT", c.value)
+		if got := Int("CONFIG_TEST_INT", c.def, c.min); got != c.want {
+			t.Errorf("Int(%q, def %d, min %d) = %d, want %d", c.value, c.def, c.min, got, c.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 30 * time.Second},
+		{"2m", 2 * time.Minute},
+		{"30", 30 * time.Second},
+		{"0s", 30 * time.Second},
+		{"-5s", 30 * time.Second},
+	}
+	for _, c := range cases {
+		t.Setenv("CONFIG_TEST_DURATION", c.value)
+		if got := Duration("CONFIG_TEST_DURATION", 30*time.Second); got != c.want {
+			t.Errorf("Duration(%q) = %s, want %s", c.value, got, c.want)
+		}
+	}
+}
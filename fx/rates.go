@@ -0,0 +1,186 @@
+// fx/rates.go
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoRate means the rate table has no rate for a currency pair on a day
+var ErrNoRate = errors.New("no exchange rate")
+
+// ReportingCurrency is the currency stored amounts are normalized to, read from
+// REPORTING_CURRENCY (default USD)
+var ReportingCurrency = reportingCurrencyFromEnv()
+
+func reportingCurrencyFromEnv() string {
+	if code := NormalizeCode(os.Getenv("REPORTING_CURRENCY")); code != "" {
+		return code
+	}
+	return "USD"
+}
+
+// Rates looks up exchange rates. Rate returns how many units of to one unit of
+// from was worth on day.
+type Rates interface {
+	Rate(from, to string, day time.Time) (float64, error)
+}
+
+// Default is the rate table ingestion normalizes amounts with. It only knows
+// identity conversions until LoadDefault replaces it.
+var Default Rates = NewTable()
+
+// LoadDefault replaces Default with the CSV table at FX_RATES_FILE, if set
+func LoadDefault() error {
+	path := os.Getenv("FX_RATES_FILE")
+	if path == "" {
+		return nil
+	}
+	table, err := LoadCSV(path)
+	if err != nil {
+		return err
+	}
+	Default = table
+	return nil
+}
+
+// NormalizeCode upper-cases and trims an ISO 4217 currency code
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidCode reports whether code looks like an ISO 4217 code (three letters)
+func ValidCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+type pair struct{ from, to string }
+
+type datedRate struct {
+	day  time.Time
+	rate float64
+}
+
+// Table is an in-memory Rates. A rate applies from its day until the next
+// rate for the same pair, and a pair's inverse is used when only the other
+// direction is known.
+type Table struct {
+	rates map[pair][]datedRate // sorted by day
+}
+
+// NewTable returns an empty table
+func NewTable() *Table {
+	return &Table{rates: make(map[pair][]datedRate)}
+}
+
+// Add records that one unit of from was worth rate units of to from day onwards
+func (t *Table) Add(day time.Time, from, to string, rate float64) {
+	p := pair{NormalizeCode(from), NormalizeCode(to)}
+	day = truncateDay(day)
+	rates := t.rates[p]
+	i := sort.Search(len(rates), func(i int) bool { return !rates[i].day.Before(day) })
+	if i < len(rates) && rates[i].day.Equal(day) {
+		rates[i].rate = rate
+		return
+	}
+	rates = append(rates, datedRate{})
+	copy(rates[i+1:], rates[i:])
+	rates[i] = datedRate{day, rate}
+	t.rates[p] = rates
+}
+
+// Rate implements Rates
+func (t *Table) Rate(from, to string, day time.Time) (float64, error) {
+	from, to = NormalizeCode(from), NormalizeCode(to)
+	if from == to {
+		return 1, nil
+	}
+	day = truncateDay(day)
+	if rate, ok := t.lookup(pair{from, to}, day); ok {
+		return rate, nil
+	}
+	if rate, ok := t.lookup(pair{to, from}, day); ok {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("%w from %s to %s on %s", ErrNoRate, from, to, day.Format("2006-01-02"))
+}
+
+// lookup returns the latest rate for p on or before day
+func (t *Table) lookup(p pair, day time.Time) (float64, bool) {
+	rates := t.rates[p]
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].day.After(day) })
+	if i == 0 {
+		return 0, false
+	}
+	return rates[i-1].rate, true
+}
+
+// LoadCSV reads a rate table from a CSV file with the header
+// date,from,to,rate, e.g. "2024-01-01,EUR,USD,1.1046"
+func LoadCSV(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %w", err)
+	}
+	defer f.Close()
+
+	table, err := ReadCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// ReadCSV reads a rate table in the LoadCSV format from r
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	table := NewTable()
+	for i, rec := range records {
+		if i == 0 && strings.EqualFold(rec[0], "date") {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", i+1, rec[0])
+		}
+		from, to := NormalizeCode(rec[1]), NormalizeCode(rec[2])
+		if !ValidCode(from) || !ValidCode(to) {
+			return nil, fmt.Errorf("line %d: invalid currency pair %q/%q", i+1, rec[1], rec[2])
+		}
+		rate, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: invalid rate %q", i+1, rec[3])
+		}
+		table.Add(day, from, to, rate)
+	}
+	return table, nil
+}
+
+// truncateDay drops the time-of-day component, keeping the date in UTC
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,57 @@
+package fx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestTableRate(t *testing.T) {
+	table, err := ReadCSV(strings.NewReader(`date,from,to,rate
+# monthly EUR rates
+2024-01-01,EUR,USD,1.10
+2024-02-01,eur,usd,1.08
+2024-01-01,USD,GBP,0.80
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to, day string
+		want          float64
+	}{
+		{"EUR", "USD", "2024-01-15", 1.10},
+		{"EUR", "USD", "2024-02-01", 1.08},
+		{"EUR", "USD", "2025-06-30", 1.08},
+		{"GBP", "USD", "2024-03-01", 1.25}, // inverse of USD/GBP
+		{"JPY", "JPY", "2020-01-01", 1},
+	}
+	for _, tt := range tests {
+		got, err := table.Rate(tt.from, tt.to, day(tt.day))
+		if err != nil || got != tt.want {
+			t.Errorf("Rate(%s, %s, %s) = %v, %v; want %v", tt.from, tt.to, tt.day, got, err, tt.want)
+		}
+	}
+
+	// Nothing before the first rate, and no cross rates
+	for _, tt := range [][3]string{{"EUR", "USD", "2023-12-31"}, {"EUR", "GBP", "2024-03-01"}} {
+		if _, err := table.Rate(tt[0], tt[1], day(tt[2])); !errors.Is(err, ErrNoRate) {
+			t.Errorf("Rate(%s, %s, %s) err = %v, want ErrNoRate", tt[0], tt[1], tt[2], err)
+		}
+	}
+}
+
+func TestReadCSVRejectsBadRows(t *testing.T) {
+	for _, line := range []string{"2024-01-01,EUR,USD,0", "2024-13-01,EUR,USD,1.1", "2024-01-01,EURO,USD,1.1"} {
+		if _, err := ReadCSV(strings.NewReader(line)); err == nil {
+			t.Errorf("ReadCSV(%q) succeeded, want error", line)
+		}
+	}
+}
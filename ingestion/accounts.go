@@ -0,0 +1,158 @@
+// ingestion/accounts.go
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"campaign-analytics/config"
+	"campaign-analytics/fx"
+)
+
+// Account is one ad account to ingest. Credentials maps a credential name
+// (e.g. "access_token") to the env var holding the secret, so the config file
+// itself never contains secrets.
+type Account struct {
+	Platform    string            `json:"platform"`
+	AccountID   string            `json:"account_id"`
+	Credentials map[string]string `json:"credentials"`
+	Schedule    string            `json:"schedule,omitempty"`
+	// Tags group accounts (e.g. by client); INGESTION_TAGS polls only the
+	// accounts carrying one of the listed tags
+	Tags []string `json:"tags,omitempty"`
+
+	// Timezone is the IANA zone the platform reports days in (e.g.
+	// "America/New_York"); it defaults to INGESTION_TIMEZONE, then UTC
+	Timezone string `json:"timezone,omitempty"`
+	// Currency is the ISO 4217 code the account is billed in (e.g. "EUR");
+	// it defaults to the reporting currency
+	Currency string `json:"currency,omitempty"`
+}
+
+// CurrencyCode returns the account's currency
+func (a Account) CurrencyCode() string {
+	if code := fx.NormalizeCode(a.Currency); code != "" {
+		return code
+	}
+	return fx.ReportingCurrency
+}
+
+// Location returns the account's reporting timezone
+func (a Account) Location() (*time.Location, error) {
+	name := a.Timezone
+	if name == "" {
+		name = config.String("INGESTION_TIMEZONE", "UTC")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid timezone %q: %w", a, name, err)
+	}
+	return loc, nil
+}
+
+// Credential resolves a named credential through its env var reference
+func (a Account) Credential(name string) string {
+	if env, ok := a.Credentials[name]; ok {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// String identifies the account in logs
+func (a Account) String() string {
+	return normalizeSourceName(a.Platform) + "/" + a.AccountID
+}
+
+// AccountsConfig is the on-disk format of INGESTION_ACCOUNTS_FILE
+type AccountsConfig struct {
+	Accounts []Account `json:"accounts"`
+}
+
+// LoadAccounts reads the accounts file at path. With an empty path it falls
+// back to one account per platform built from the single-account env vars.
+func LoadAccounts(path string) ([]Account, error) {
+	if path == "" {
+		return envAccounts(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read accounts file: %w", err)
+	}
+	var cfg AccountsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse accounts file: %w", err)
+	}
+
+	seen := make(map[string]int)
+	for i, acct := range cfg.Accounts {
+		if acct.Platform == "" || acct.AccountID == "" {
+			return nil, fmt.Errorf("accounts[%d]: platform and account_id are required", i)
+		}
+		cfg.Accounts[i].Platform = normalizeSourceName(acct.Platform)
+		if first, ok := seen[cfg.Accounts[i].String()]; ok {
+			return nil, fmt.Errorf("accounts[%d]: %s is already listed as accounts[%d]", i, cfg.Accounts[i], first)
+		}
+		seen[cfg.Accounts[i].String()] = i
+		if len(acct.Credentials) == 0 {
+			return nil, fmt.Errorf("accounts[%d]: credentials are required", i)
+		}
+		for name, env := range acct.Credentials {
+			if env == "" {
+				return nil, fmt.Errorf("accounts[%d]: credential %q has no env var", i, name)
+			}
+		}
+		if _, err := cfg.Accounts[i].Location(); err != nil {
+			return nil, fmt.Errorf("accounts[%d]: %w", i, err)
+		}
+		if !fx.ValidCode(cfg.Accounts[i].CurrencyCode()) {
+			return nil, fmt.Errorf("accounts[%d]: invalid currency %q", i, acct.Currency)
+		}
+	}
+	return cfg.Accounts, nil
+}
+
+// envAccounts describes the legacy single-account env vars for each platform
+func envAccounts() []Account {
+	return []Account{
+		{
+			Platform:    "meta",
+			AccountID:   os.Getenv("META_AD_ACCOUNT_ID"),
+			Credentials: map[string]string{"access_token": "META_ACCESS_TOKEN"},
+		},
+		{
+			Platform:  "google",
+			AccountID: os.Getenv("GOOGLE_ADS_CUSTOMER_ID"),
+			Credentials: map[string]string{
+				"access_token":      "GOOGLE_ADS_ACCESS_TOKEN",
+				"refresh_token":     "GOOGLE_ADS_REFRESH_TOKEN",
+				"client_id":         "GOOGLE_ADS_CLIENT_ID",
+				"client_secret":     "GOOGLE_ADS_CLIENT_SECRET",
+				"developer_token":   "GOOGLE_ADS_DEVELOPER_TOKEN",
+				"login_customer_id": "GOOGLE_ADS_LOGIN_CUSTOMER_ID",
+			},
+		},
+		{
+			Platform:  "tiktok",
+			AccountID: os.Getenv("TIKTOK_ADVERTISER_ID"),
+			Credentials: map[string]string{
+				"access_token":  "TIKTOK_ACCESS_TOKEN",
+				"refresh_token": "TIKTOK_REFRESH_TOKEN",
+				"client_id":     "TIKTOK_APP_ID",
+				"client_secret": "TIKTOK_APP_SECRET",
+			},
+		},
+		{
+			Platform:  "linkedin",
+			AccountID: os.Getenv("LINKEDIN_ACCOUNT_ID"),
+			Credentials: map[string]string{
+				"access_token":  "LINKEDIN_ACCESS_TOKEN",
+				"refresh_token": "LINKEDIN_REFRESH_TOKEN",
+				"client_id":     "LINKEDIN_CLIENT_ID",
+				"client_secret": "LINKEDIN_CLIENT_SECRET",
+			},
+		},
+	}
+}
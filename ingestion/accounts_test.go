@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAccounts(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: `{"accounts": [
+				{"platform": "Meta", "account_id": "act_1", "credentials": {"access_token": "META_TOKEN_A"}, "tags": ["client-a"]},
+				{"platform": "meta", "account_id": "act_2", "credentials": {"access_token": "META_TOKEN_B"}, "currency": "eur"}]}`,
+		},
+		{
+			name:    "no credentials",
+			file:    `{"accounts": [{"platform": "meta", "account_id": "act_1"}]}`,
+			wantErr: "credentials are required",
+		},
+		{
+			name:    "credential without env var",
+			file:    `{"accounts": [{"platform": "meta", "account_id": "act_1", "credentials": {"access_token": ""}}]}`,
+			wantErr: `credential "access_token" has no env var`,
+		},
+		{
+			name: "duplicate account",
+			file: `{"accounts": [
+				{"platform": "meta", "account_id": "act_1", "credentials": {"access_token": "META_TOKEN_A"}},
+				{"platform": "Meta", "account_id": "act_1", "credentials": {"access_token": "META_TOKEN_B"}}]}`,
+			wantErr: "accounts[1]: meta/act_1 is already listed as accounts[0]",
+		},
+		{
+			name:    "missing account id",
+			file:    `{"accounts": [{"platform": "meta", "credentials": {"access_token": "META_TOKEN_A"}}]}`,
+			wantErr: "platform and account_id are required",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "accounts.json")
+			if err := os.WriteFile(path, []byte(tc.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			accounts, err := LoadAccounts(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(accounts) != 2 || accounts[0].Platform != "meta" || accounts[1].CurrencyCode() != "EUR" {
+				t.Fatalf("accounts = %+v", accounts)
+			}
+		})
+	}
+}
+
+func TestFilterTagged(t *testing.T) {
+	accounts := []Account{
+		{Platform: "meta", AccountID: "a", Tags: []string{"client-a", "retail"}},
+		{Platform: "meta", AccountID: "b", Tags: []string{"client-b"}},
+		{Platform: "google", AccountID: "c"},
+	}
+	kept := filterTagged(accounts, map[string]bool{"retail": true, "b2b": true})
+	if len(kept) != 1 || kept[0].AccountID != "a" {
+		t.Errorf("kept %v, want [meta/a]", kept)
+	}
+}
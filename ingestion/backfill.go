@@ -0,0 +1,160 @@
+// ingestion/backfill.go
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+// BackfillCheckpoint records how far a backfill has progressed so it can resume
+type BackfillCheckpoint struct {
+	Source string `json:"source"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Next   string `json:"next"` // first day not yet fetched
+}
+
+// Backfill fetches [from, to] from src in chunks of chunkDays and passes each
+// chunk's rows to store in one call. Every chunk is recorded as an ingestion
+// run of accountID. Progress is written to checkpointPath after each chunk,
+// and an existing checkpoint for the same source and range is resumed from.
+func Backfill(ctx context.Context, src Source, accountID string, from, to time.Time, chunkDays int, checkpointPath string,
+	store func([]models.CampaignMetrics) ([]storage.WriteResult, error)) error {
+	if chunkDays < 1 {
+		return fmt.Errorf("chunk size must be at least 1 day")
+	}
+	from, to = truncateDay(from), truncateDay(to)
+	if to.Before(from) {
+		return fmt.Errorf("--to %s is before --from %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	cp := BackfillCheckpoint{
+		Source: src.Name(),
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Next:   from.Format("2006-01-02"),
+	}
+	if saved, err := loadCheckpoint(checkpointPath); err != nil {
+		return err
+	} else if saved != nil && saved.Source == cp.Source && saved.From == cp.From && saved.To == cp.To {
+		cp.Next = saved.Next
+		fmt.Printf("[BACKFILL] Resuming %s from checkpoint at %s\n", cp.Source, cp.Next)
+	}
+
+	start, err := time.Parse("2006-01-02", cp.Next)
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint date %q: %w", cp.Next, err)
+	}
+
+	for !start.After(to) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start.AddDate(0, 0, chunkDays-1)
+		if end.After(to) {
+			end = to
+		}
+
+		fmt.Printf("[BACKFILL] %s: fetching %s to %s\n", cp.Source, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		if err := backfillChunk(ctx, src, accountID, Window{From: start, To: end}, store); err != nil {
+			return fmt.Errorf("%s to %s: %w", start.Format("2006-01-02"), end.Format("2006-01-02"), err)
+		}
+
+		start = end.AddDate(0, 0, 1)
+		cp.Next = start.Format("2006-01-02")
+		if err := saveCheckpoint(checkpointPath, cp); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("[BACKFILL] %s: completed %s to %s\n", cp.Source, cp.From, cp.To)
+	return nil
+}
+
+// backfillChunk fetches and stores one window, recording it as an ingestion
+// run just like a scheduled poll
+func backfillChunk(ctx context.Context, src Source, accountID string, window Window,
+	store func([]models.CampaignMetrics) ([]storage.WriteResult, error)) error {
+	run := &models.IngestionRun{
+		Source:     src.Name(),
+		AccountID:  accountID,
+		WindowFrom: window.From,
+		WindowTo:   window.To,
+		StartedAt:  time.Now().UTC(),
+	}
+	// The run is recorded even when Ctrl-C interrupted the chunk
+	defer func() {
+		run.FinishedAt = time.Now().UTC()
+		recordRun(context.WithoutCancel(ctx), *run)
+	}()
+
+	metrics, err := src.Fetch(ctx, window)
+	if err == nil {
+		err = normalizeCurrency(metrics)
+	}
+	if err != nil {
+		setRunError(run, err)
+		return fmt.Errorf("fetch: %w", err)
+	}
+	run.RowsFetched = len(metrics)
+
+	for i := range metrics {
+		if metrics[i].AccountID == "" {
+			metrics[i].AccountID = accountID
+		}
+	}
+	results, err := store(metrics)
+	if err != nil {
+		run.RowsFailed = len(metrics)
+		return fmt.Errorf("store: %w", err)
+	}
+	for _, result := range results {
+		countWrite(run, result, nil)
+	}
+	return nil
+}
+
+// loadCheckpoint reads a checkpoint file, returning nil if it does not exist
+func loadCheckpoint(path string) (*BackfillCheckpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	var cp BackfillCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint writes the checkpoint atomically via a temp file and rename
+func saveCheckpoint(path string, cp BackfillCheckpoint) error {
+	if path == "" {
+		return nil
+	}
+	data, _ := json.MarshalIndent(cp, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// truncateDay drops the time-of-day component, keeping the date in UTC
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
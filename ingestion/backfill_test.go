@@ -0,0 +1,73 @@
+package ingestion
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Name() string { return "fake" }
+
+func (fakeSource) Fetch(ctx context.Context, w Window) ([]models.CampaignMetrics, error) {
+	var rows []models.CampaignMetrics
+	for d := w.From; !d.After(w.To); d = d.AddDate(0, 0, 1) {
+		rows = append(rows, models.CampaignMetrics{CampaignID: "cmp-1", Timestamp: d, Date: d.Format("2006-01-02")})
+	}
+	return rows, nil
+}
+
+type runCapture struct {
+	mu   sync.Mutex
+	runs []models.IngestionRun
+}
+
+func (c *runCapture) RecordRun(ctx context.Context, run models.IngestionRun) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runs = append(c.runs, run)
+	return nil
+}
+
+func TestBackfillRecordsEveryChunk(t *testing.T) {
+	capture := &runCapture{}
+	saved := RunLogs
+	RunLogs = []RunRecorder{capture}
+	t.Cleanup(func() { RunLogs = saved })
+
+	// The second row of every chunk is already stored
+	store := func(rows []models.CampaignMetrics) ([]storage.WriteResult, error) {
+		results := make([]storage.WriteResult, len(rows))
+		for i, m := range rows {
+			if m.AccountID != "act_1" {
+				t.Errorf("row stored with account %q, want act_1", m.AccountID)
+			}
+			results[i] = storage.WriteInserted
+			if i == 1 {
+				results[i] = storage.WriteSkipped
+			}
+		}
+		return results, nil
+	}
+	from := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 5, 0, 0, 0, 0, time.UTC)
+	if err := Backfill(context.Background(), fakeSource{}, "act_1", from, to, 3, "", store); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(capture.runs) != 2 {
+		t.Fatalf("recorded %d runs, want one per chunk (2)", len(capture.runs))
+	}
+	first, second := capture.runs[0], capture.runs[1]
+	if first.Source != "fake" || first.AccountID != "act_1" || !first.WindowFrom.Equal(from) || !second.WindowTo.Equal(to) {
+		t.Errorf("runs = %+v, %+v; want fake/act_1 covering 04-01..04-05", first, second)
+	}
+	if first.RowsFetched != 3 || first.RowsInserted != 2 || first.RowsDeduped != 1 || second.RowsFetched != 2 || second.RowsInserted != 1 {
+		t.Errorf("run counts = %+v, %+v", first, second)
+	}
+}
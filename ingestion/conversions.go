@@ -0,0 +1,75 @@
+// ingestion/conversions.go
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"campaign-analytics/models"
+)
+
+// defaultMetaConversionActionTypes is used when META_CONVERSION_ACTION_TYPES is unset.
+// "purchase" already aggregates pixel, app and on-site purchases, so listing
+// those as well would double count.
+var defaultMetaConversionActionTypes = []string{"purchase"}
+
+// metaConversionActionTypesFromEnv reads the comma-separated list of Meta action
+// types that count as conversions (and whose action_values count as revenue)
+func metaConversionActionTypesFromEnv() []string {
+	raw := os.Getenv("META_CONVERSION_ACTION_TYPES")
+	if strings.TrimSpace(raw) == "" {
+		return defaultMetaConversionActionTypes
+	}
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// metaAction is one entry of a Meta `actions` or `action_values` list
+type metaAction struct {
+	ActionType string `json:"action_type"`
+	Value      string `json:"value"`
+}
+
+// sumMetaActions totals the values of the actions whose type is in types. Action
+// values are money and action counts can be fractional, so the sum is exact micros.
+func sumMetaActions(actions []metaAction, types []string) (models.Micros, error) {
+	var total models.Micros
+	for _, a := range actions {
+		for _, t := range types {
+			if a.ActionType == t {
+				v, err := models.ParseMicros(a.Value)
+				if err != nil {
+					return 0, fmt.Errorf("%s: %w", a.ActionType, err)
+				}
+				total += v
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// flexFloat decodes a JSON number that platforms sometimes send as a string
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*f = 0
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
@@ -0,0 +1,37 @@
+// ingestion/currency.go
+package ingestion
+
+import (
+	"fmt"
+	"time"
+
+	"campaign-analytics/fx"
+	"campaign-analytics/models"
+)
+
+// normalizeCurrency converts each row's amounts from its Currency to the
+// reporting currency using fx.Default, keeping the platform's amounts as the
+// originals. Rows without a currency are taken to be in the reporting
+// currency already. It fails if any row has no rate for its day, so amounts
+// in different currencies are never stored as if they were the same.
+func normalizeCurrency(metrics []models.CampaignMetrics) error {
+	for i := range metrics {
+		m := &metrics[i]
+		if m.Currency == "" {
+			m.Currency = fx.ReportingCurrency
+		}
+
+		day := m.Timestamp
+		if d, err := time.Parse("2006-01-02", m.Date); err == nil {
+			day = d
+		}
+		rate, err := fx.Default.Rate(m.Currency, fx.ReportingCurrency, day)
+		if err != nil {
+			return fmt.Errorf("normalize %s: %w", m.CampaignID, err)
+		}
+
+		m.OriginalCost, m.OriginalRevenue, m.FXRate = m.Cost, m.Revenue, rate
+		m.Cost, m.Revenue = m.Cost.Convert(rate), m.Revenue.Convert(rate)
+	}
+	return nil
+}
@@ -2,45 +2,286 @@
 package ingestion
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"campaign-analytics/config"
+	"campaign-analytics/models"
+	"campaign-analytics/processor"
+	"campaign-analytics/storage"
 )
 
-// StartRealFetcher determines which APIs to call based on env flags
-func StartRealFetcher() {
+// defaultLookbackDays is how far back each polling cycle asks sources for data
+const defaultLookbackDays = 7
+
+// defaultJitter is the largest random delay added to each scheduled poll
+const defaultJitter = 30 * time.Second
+
+// StartRealFetcher polls every configured account on its own schedule. Accounts
+// come from INGESTION_ACCOUNTS_FILE (or the single-account env vars), optionally
+// narrowed down to the platforms listed in ENABLED_SOURCES and the tags listed
+// in INGESTION_TAGS. Accounts run in parallel, limited per platform, and a poll
+// is skipped while the previous one for the same account is still running.
+// Once ctx is cancelled no new polls start, and StartRealFetcher returns after
+// the in-flight ones have finished.
+func StartRealFetcher(ctx context.Context) {
 	fmt.Println("[DISPATCHER] Starting real API ingestion mode...")
 
-	enabledSources := strings.Split(os.Getenv("ENABLED_SOURCES"), ",")
-	sourceMap := make(map[string]bool)
-	for _, src := range enabledSources {
-		sourceMap[strings.TrimSpace(strings.ToLower(src))] = true
+	accountsFile := os.Getenv("INGESTION_ACCOUNTS_FILE")
+	accounts, err := LoadAccounts(accountsFile)
+	if err != nil {
+		fmt.Printf("[DISPATCHER] Failed to load accounts: %v\n", err)
+		return
+	}
+	// With an accounts file and no ENABLED_SOURCES, every listed account is polled
+	if enabled := enabledSources(); len(enabled) > 0 || accountsFile == "" {
+		accounts = filterEnabled(accounts, enabled)
+	}
+	if tags := enabledTags(); len(tags) > 0 {
+		accounts = filterTagged(accounts, tags)
+	}
+
+	// Sources are built once so token providers keep their cached tokens
+	var jobs []*accountJob
+	for _, acct := range accounts {
+		src, err := NewSource(acct)
+		if err != nil {
+			fmt.Printf("[DISPATCHER] %s: %v\n", acct, err)
+			continue
+		}
+		sched, err := scheduleFor(acct)
+		if err != nil {
+			fmt.Printf("[DISPATCHER] %s: %v\n", acct, err)
+			continue
+		}
+		jobs = append(jobs, &accountJob{acct: acct, src: src, schedule: sched, slots: platformSlots(src.Name())})
+	}
+	fmt.Printf("[DISPATCHER] Polling %d account(s)\n", len(jobs))
+
+	jitter := defaultJitter
+	if d, err := time.ParseDuration(os.Getenv("INGESTION_JITTER")); err == nil && d >= 0 {
+		jitter = d
+	}
+
+	runCtx, cancelRuns := withGrace(ctx, config.ShutdownTimeout())
+	defer cancelRuns()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(j *accountJob) {
+			defer wg.Done()
+			j.loop(ctx, runCtx, jitter)
+		}(job)
+	}
+	wg.Wait()
+	pendingRuns.Wait()
+	fmt.Println("[DISPATCHER] Stopped")
+}
+
+// accountJob pairs a configured account with the Source built for it
+type accountJob struct {
+	acct     Account
+	src      Source
+	schedule Schedule
+	slots    chan struct{} // per-platform concurrency semaphore
+	running  atomic.Bool
+	inFlight sync.WaitGroup
+}
+
+// loop polls the account on every schedule tick (each with a random jitter
+// delay) until ctx is done. Interval schedules also poll once at startup; cron
+// schedules wait for their first fire time. It then waits for the in-flight
+// poll, which runs under runCtx so that a batch already being fetched is
+// written in full.
+func (j *accountJob) loop(ctx, runCtx context.Context, jitter time.Duration) {
+	defer j.inFlight.Wait()
+
+	next := time.Now()
+	if _, ok := j.schedule.(intervalSchedule); !ok {
+		next = j.schedule.Next(next)
+	}
+	for {
+		if next.IsZero() {
+			fmt.Printf("[DISPATCHER] %s schedule never fires again, stopping its polls\n", j.acct)
+			return
+		}
+		wait := time.Until(next) + randomDelay(jitter)
+		if err := sleepContext(ctx, wait); err != nil {
+			return
+		}
+		if j.running.CompareAndSwap(false, true) {
+			j.inFlight.Add(1)
+			go func() {
+				defer j.inFlight.Done()
+				defer j.running.Store(false)
+				select {
+				case j.slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-j.slots }()
+				j.run(runCtx, LastNDays(time.Now().UTC(), defaultLookbackDays))
+			}()
+		} else {
+			fmt.Printf("[DISPATCHER] %s previous poll still running, skipping this one\n", j.acct)
+		}
+		next = j.schedule.Next(time.Now())
+	}
+}
+
+// withGrace returns a context that outlives ctx by grace: it is cancelled
+// grace after ctx is done (or by cancel). Polls run under it so a shutdown lets
+// them finish, but a platform back-off or limiter pause can't hold it up for
+// longer than the grace period.
+func withGrace(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		t := time.AfterFunc(grace, cancel)
+		context.AfterFunc(graceCtx, func() { t.Stop() })
+	})
+	return graceCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// pendingRuns tracks runs whose rows are still queued in the processor
+var pendingRuns sync.WaitGroup
+
+// run fetches one account and queues every row for the processor. The run is
+// recorded once the processor has reported back on all of them, so the next
+// poll doesn't wait on the database.
+func (j *accountJob) run(ctx context.Context, window Window) {
+	run := &models.IngestionRun{
+		Source:     j.src.Name(),
+		AccountID:  j.acct.AccountID,
+		WindowFrom: window.From,
+		WindowTo:   window.To,
+		StartedAt:  time.Now().UTC(),
 	}
 
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+	metrics, err := j.src.Fetch(ctx, window)
+	if err == nil {
+		err = normalizeCurrency(metrics)
+	}
+	if err != nil {
+		setRunError(run, err)
+		run.FinishedAt = time.Now().UTC()
+		recordRun(ctx, *run)
+		return
+	}
+	run.RowsFetched = len(metrics)
 
-	callAll := func() {
-		if sourceMap["meta"] {
-			FetchMetaCampaigns()
+	var mu sync.Mutex
+	var writes sync.WaitGroup
+	for _, m := range metrics {
+		if m.AccountID == "" {
+			m.AccountID = j.acct.AccountID
 		}
-		if sourceMap["google"] {
-			FetchGoogleCampaigns()
+		writes.Add(1)
+		err := processor.Submit(ctx, m, func(result storage.WriteResult, err error) {
+			mu.Lock()
+			countWrite(run, result, err)
+			mu.Unlock()
+			writes.Done()
+		})
+		if err != nil {
+			mu.Lock()
+			countWrite(run, storage.WriteSkipped, err)
+			mu.Unlock()
+			writes.Done()
 		}
-		if sourceMap["tiktok"] {
-			FetchTiktokCampaigns()
+	}
+
+	pendingRuns.Add(1)
+	go func() {
+		defer pendingRuns.Done()
+		writes.Wait()
+		run.FinishedAt = time.Now().UTC()
+		recordRun(ctx, *run)
+	}()
+}
+
+// defaultPlatformConcurrency is how many accounts of one platform may be fetched at once
+const defaultPlatformConcurrency = 2
+
+var (
+	slotsMu sync.Mutex
+	slots   = make(map[string]chan struct{})
+)
+
+// platformSlots returns the shared concurrency semaphore for a platform, sized by
+// INGESTION_CONCURRENCY_<PLATFORM>, then INGESTION_CONCURRENCY, then 2
+func platformSlots(platform string) chan struct{} {
+	slotsMu.Lock()
+	defer slotsMu.Unlock()
+	if s, ok := slots[platform]; ok {
+		return s
+	}
+	n := config.Int("INGESTION_CONCURRENCY_"+strings.ToUpper(platform), config.Int("INGESTION_CONCURRENCY", defaultPlatformConcurrency, 1), 1)
+	s := make(chan struct{}, n)
+	slots[platform] = s
+	return s
+}
+
+// randomDelay returns a random duration in [0, max]
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// filterEnabled keeps the accounts whose platform is enabled
+func filterEnabled(accounts []Account, enabled map[string]bool) []Account {
+	var kept []Account
+	for _, acct := range accounts {
+		if enabled[normalizeSourceName(acct.Platform)] {
+			kept = append(kept, acct)
 		}
-		if sourceMap["linkedin"] {
-			FetchLinkedInCampaigns()
+	}
+	return kept
+}
+
+// enabledSources parses the comma-separated ENABLED_SOURCES env var
+func enabledSources() map[string]bool {
+	sourceMap := make(map[string]bool)
+	for _, src := range strings.Split(os.Getenv("ENABLED_SOURCES"), ",") {
+		if name := normalizeSourceName(src); name != "" {
+			sourceMap[name] = true
 		}
 	}
+	return sourceMap
+}
 
-	// Initial call
-	callAll()
+// filterTagged keeps the accounts that carry at least one of tags
+func filterTagged(accounts []Account, tags map[string]bool) []Account {
+	var kept []Account
+	for _, acct := range accounts {
+		for _, tag := range acct.Tags {
+			if tags[tag] {
+				kept = append(kept, acct)
+				break
+			}
+		}
+	}
+	return kept
+}
 
-	for {
-		<-ticker.C
-		callAll()
+// enabledTags parses the comma-separated INGESTION_TAGS env var
+func enabledTags() map[string]bool {
+	tags := make(map[string]bool)
+	for _, tag := range strings.Split(os.Getenv("INGESTION_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags[tag] = true
+		}
 	}
+	return tags
 }
@@ -0,0 +1,86 @@
+package ingestion
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+// blockingSource counts fetches and holds each one until release is closed
+type blockingSource struct {
+	fetches atomic.Int32
+	release chan struct{}
+}
+
+func (s *blockingSource) Name() string { return "fake" }
+
+func (s *blockingSource) Fetch(ctx context.Context, w Window) ([]models.CampaignMetrics, error) {
+	s.fetches.Add(1)
+	<-s.release
+	return nil, nil
+}
+
+func TestDispatcherSkipsTickWhilePollRuns(t *testing.T) {
+	saved := RunLogs
+	RunLogs = []RunRecorder{&runCapture{}}
+	t.Cleanup(func() { RunLogs = saved })
+
+	src := &blockingSource{release: make(chan struct{})}
+	job := &accountJob{acct: Account{Platform: "fake", AccountID: "act_1"}, src: src,
+		schedule: intervalSchedule(5 * time.Millisecond), slots: make(chan struct{}, 4)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		job.loop(ctx, ctx, 0)
+		close(done)
+	}()
+
+	// About ten ticks pass while the first fetch is still running
+	time.Sleep(50 * time.Millisecond)
+	if n := src.fetches.Load(); n != 1 {
+		t.Errorf("%d fetches started while the first was running, want 1", n)
+	}
+
+	cancel()
+	close(src.release)
+	<-done
+	pendingRuns.Wait()
+}
+
+func TestDispatcherCronWaitsForFirstFire(t *testing.T) {
+	sched, err := ParseSchedule("0 0 1 1 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &blockingSource{release: make(chan struct{})}
+	close(src.release)
+	job := &accountJob{acct: Account{Platform: "fake", AccountID: "act_1"}, src: src,
+		schedule: sched, slots: make(chan struct{}, 1)}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	job.loop(ctx, ctx, 0)
+	if n := src.fetches.Load(); n != 0 {
+		t.Errorf("cron schedule polled %d times at startup, want 0", n)
+	}
+}
+
+func TestWithGraceOutlivesShutdown(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	runCtx, cancel := withGrace(ctx, 30*time.Millisecond)
+	defer cancel()
+
+	shutdown()
+	time.Sleep(10 * time.Millisecond)
+	if runCtx.Err() != nil {
+		t.Fatal("run context cancelled as soon as shutdown started")
+	}
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("run context still alive after the grace period")
+	}
+}
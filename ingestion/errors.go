@@ -0,0 +1,228 @@
+// ingestion/errors.go
+package ingestion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Error kinds shared by all platforms. Match them with errors.Is.
+var (
+	ErrAuthExpired      = errors.New("auth expired")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrPlatformServer   = errors.New("platform server error")
+	ErrUnknownAPI       = errors.New("unexpected API error")
+)
+
+// PlatformError is a failed API call with the platform's own error code and message
+type PlatformError struct {
+	Platform   string
+	Kind       error // one of the Err* kinds above
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *PlatformError) Error() string {
+	msg := fmt.Sprintf("%s (HTTP %d", e.Kind, e.StatusCode)
+	if e.Code != "" {
+		msg += ", code " + e.Code
+	}
+	msg += ")"
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is lets errors.Is(err, ErrAuthExpired) and friends match on Kind
+func (e *PlatformError) Is(target error) bool { return target == e.Kind }
+
+// KindName is the short machine-readable name of the error kind
+func (e *PlatformError) KindName() string {
+	switch e.Kind {
+	case ErrAuthExpired:
+		return "auth_expired"
+	case ErrPermissionDenied:
+		return "permission_denied"
+	case ErrQuotaExceeded:
+		return "quota_exceeded"
+	case ErrInvalidRequest:
+		return "invalid_request"
+	case ErrPlatformServer:
+		return "server_error"
+	}
+	return "unknown"
+}
+
+// kindForStatus is the fallback classification when a body has nothing better
+func kindForStatus(status int) error {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrAuthExpired
+	case status == http.StatusForbidden:
+		return ErrPermissionDenied
+	case status == http.StatusTooManyRequests:
+		return ErrQuotaExceeded
+	case status >= 500:
+		return ErrPlatformServer
+	case status >= 400:
+		return ErrInvalidRequest
+	}
+	return ErrUnknownAPI
+}
+
+// parsePlatformError builds a PlatformError from a non-200 response body
+func parsePlatformError(platform string, status int, body []byte) *PlatformError {
+	e := &PlatformError{Platform: platform, Kind: kindForStatus(status), StatusCode: status}
+	switch platform {
+	case "meta":
+		parseMetaError(e, body)
+	case "google":
+		parseGoogleError(e, body)
+	case "linkedin":
+		parseLinkedInError(e, body)
+	case "tiktok":
+		parseTiktokError(e, body)
+	}
+	if e.Message == "" && len(body) > 0 && len(body) <= 512 {
+		e.Message = string(body)
+	}
+	return e
+}
+
+// parseMetaError reads {"error": {"code", "error_subcode", "message"}}
+func parseMetaError(e *PlatformError, body []byte) {
+	var resp struct {
+		Error struct {
+			Message      string `json:"message"`
+			Code         int    `json:"code"`
+			ErrorSubcode int    `json:"error_subcode"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error.Code == 0 {
+		return
+	}
+	e.Code = strconv.Itoa(resp.Error.Code)
+	if resp.Error.ErrorSubcode != 0 {
+		e.Code += "/" + strconv.Itoa(resp.Error.ErrorSubcode)
+	}
+	e.Message = resp.Error.Message
+
+	switch code := resp.Error.Code; {
+	case code == 190 || code == 102:
+		e.Kind = ErrAuthExpired
+	case code == 10 || (code >= 200 && code < 300):
+		e.Kind = ErrPermissionDenied
+	case code == 4 || code == 17 || code == 32 || code == 613 || (code >= 80000 && code <= 80014):
+		e.Kind = ErrQuotaExceeded
+	case code == 100:
+		e.Kind = ErrInvalidRequest
+	case code == 1 || code == 2:
+		e.Kind = ErrPlatformServer
+	}
+}
+
+// parseGoogleError reads the google.rpc.Status body and its GoogleAdsFailure details
+func parseGoogleError(e *PlatformError, body []byte) {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				Errors []struct {
+					ErrorCode map[string]string `json:"errorCode"`
+					Message   string            `json:"message"`
+				} `json:"errors"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error.Status == "" {
+		return
+	}
+	e.Code = resp.Error.Status
+	e.Message = resp.Error.Message
+	for _, d := range resp.Error.Details {
+		for _, fe := range d.Errors {
+			for category, code := range fe.ErrorCode {
+				e.Code = category + "." + code
+				e.Message = fe.Message
+			}
+		}
+	}
+
+	switch resp.Error.Status {
+	case "UNAUTHENTICATED":
+		e.Kind = ErrAuthExpired
+	case "PERMISSION_DENIED":
+		e.Kind = ErrPermissionDenied
+	case "RESOURCE_EXHAUSTED":
+		e.Kind = ErrQuotaExceeded
+	case "INVALID_ARGUMENT", "NOT_FOUND", "FAILED_PRECONDITION":
+		e.Kind = ErrInvalidRequest
+	case "INTERNAL", "UNAVAILABLE", "DEADLINE_EXCEEDED":
+		e.Kind = ErrPlatformServer
+	}
+}
+
+// parseLinkedInError reads {"status", "serviceErrorCode", "code", "message"}
+func parseLinkedInError(e *PlatformError, body []byte) {
+	var resp struct {
+		ServiceErrorCode int    `json:"serviceErrorCode"`
+		Code             string `json:"code"`
+		Message          string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return
+	}
+	e.Code = resp.Code
+	if e.Code == "" && resp.ServiceErrorCode != 0 {
+		e.Code = strconv.Itoa(resp.ServiceErrorCode)
+	}
+	e.Message = resp.Message
+	if resp.Code == "EXPIRED_ACCESS_TOKEN" || resp.Code == "INVALID_ACCESS_TOKEN" || resp.Code == "REVOKED_ACCESS_TOKEN" {
+		e.Kind = ErrAuthExpired
+	}
+}
+
+// tiktokErrorKinds maps TikTok business codes, which arrive in HTTP 200 bodies
+var tiktokErrorKinds = map[int]error{
+	40001: ErrAuthExpired, // authentication failed
+	40102: ErrAuthExpired, // access token expired
+	40104: ErrAuthExpired, // access token empty or invalid
+	40105: ErrAuthExpired, // access token invalid or revoked
+	40002: ErrInvalidRequest,
+	40006: ErrPermissionDenied,
+	40100: ErrQuotaExceeded, // requests too frequent
+	40133: ErrQuotaExceeded, // app QPS limit reached
+	50000: ErrPlatformServer,
+	50002: ErrPlatformServer,
+}
+
+// parseTiktokError reads {"code", "message"}
+func parseTiktokError(e *PlatformError, body []byte) {
+	var resp struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Code == 0 {
+		return
+	}
+	e.Code = strconv.Itoa(resp.Code)
+	e.Message = resp.Message
+	if kind, ok := tiktokErrorKinds[resp.Code]; ok {
+		e.Kind = kind
+	} else if e.StatusCode == http.StatusOK {
+		e.Kind = ErrUnknownAPI
+	}
+}
+
+// isRetryableKind is true for throttling and temporary platform failures
+func isRetryableKind(kind error) bool {
+	return kind == ErrQuotaExceeded || kind == ErrPlatformServer
+}
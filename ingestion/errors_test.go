@@ -0,0 +1,96 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"campaign-analytics/models"
+)
+
+func TestParsePlatformError(t *testing.T) {
+	cases := []struct {
+		platform  string
+		status    int
+		body      string
+		kind      error
+		code      string
+		retryable bool
+	}{
+		{"meta", 400, `{"error": {"message": "Error validating access token: Session has expired", "type": "OAuthException", "code": 190, "error_subcode": 463, "fbtrace_id": "A1"}}`,
+			ErrAuthExpired, "190/463", false},
+		{"meta", 403, `{"error": {"message": "(#200) Requires ads_read permission", "type": "OAuthException", "code": 200}}`,
+			ErrPermissionDenied, "200", false},
+		{"meta", 400, `{"error": {"message": "(#17) User request limit reached", "type": "OAuthException", "code": 17, "error_subcode": 2446079}}`,
+			ErrQuotaExceeded, "17/2446079", true},
+		{"meta", 400, `{"error": {"message": "(#100) Invalid parameter", "type": "OAuthException", "code": 100}}`,
+			ErrInvalidRequest, "100", false},
+		{"meta", 500, `{"error": {"message": "An unknown error occurred", "type": "OAuthException", "code": 1, "is_transient": true}}`,
+			ErrPlatformServer, "1", true},
+		{"google", 401, `{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`,
+			ErrAuthExpired, "UNAUTHENTICATED", false},
+		{"google", 429, `{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED",
+			"details": [{"@type": "type.googleapis.com/google.ads.googleads.v16.errors.GoogleAdsFailure",
+			"errors": [{"errorCode": {"quotaError": "RESOURCE_EXHAUSTED"}, "message": "Too many requests."}]}]}}`,
+			ErrQuotaExceeded, "quotaError.RESOURCE_EXHAUSTED", true},
+		{"google", 403, `{"error": {"code": 403, "message": "The caller does not have permission", "status": "PERMISSION_DENIED",
+			"details": [{"errors": [{"errorCode": {"authorizationError": "USER_PERMISSION_DENIED"}, "message": "User doesn't have permission."}]}]}}`,
+			ErrPermissionDenied, "authorizationError.USER_PERMISSION_DENIED", false},
+		{"google", 503, `{"error": {"code": 503, "message": "The service is currently unavailable.", "status": "UNAVAILABLE"}}`,
+			ErrPlatformServer, "UNAVAILABLE", true},
+		{"linkedin", 401, `{"status": 401, "serviceErrorCode": 65601, "code": "REVOKED_ACCESS_TOKEN", "message": "The token used in the request has been revoked by the user"}`,
+			ErrAuthExpired, "REVOKED_ACCESS_TOKEN", false},
+		{"linkedin", 403, `{"status": 403, "serviceErrorCode": 100, "code": "ACCESS_DENIED", "message": "Not enough permissions to access: adAnalytics"}`,
+			ErrPermissionDenied, "ACCESS_DENIED", false},
+		{"linkedin", 429, `{"status": 429, "serviceErrorCode": 101, "message": "Resource level throttle limit reached"}`,
+			ErrQuotaExceeded, "101", true},
+		{"tiktok", 200, `{"code": 40105, "message": "The access token is invalid or has been revoked.", "request_id": "r1", "data": {}}`,
+			ErrAuthExpired, "40105", false},
+		{"tiktok", 200, `{"code": 40100, "message": "Too many requests.", "request_id": "r1", "data": {}}`,
+			ErrQuotaExceeded, "40100", true},
+		{"tiktok", 200, `{"code": 40006, "message": "No permission to operate advertiser", "request_id": "r1", "data": {}}`,
+			ErrPermissionDenied, "40006", false},
+		{"tiktok", 200, `{"code": 41000, "message": "Something new", "request_id": "r1", "data": {}}`,
+			ErrUnknownAPI, "41000", false},
+		// A body the parser doesn't recognise falls back to the HTTP status
+		{"meta", 502, `<html>Bad Gateway</html>`, ErrPlatformServer, "", true},
+	}
+	for _, c := range cases {
+		e := parsePlatformError(c.platform, c.status, []byte(c.body))
+		if !errors.Is(e, c.kind) || e.Code != c.code {
+			t.Errorf("%s %d %.40s: kind %v code %q, want %v %q", c.platform, c.status, c.body, e.Kind, e.Code, c.kind, c.code)
+		}
+		if isRetryableKind(e.Kind) != c.retryable {
+			t.Errorf("%s %d %.40s: retryable = %t, want %t", c.platform, c.status, c.body, !c.retryable, c.retryable)
+		}
+	}
+}
+
+func TestSetRunErrorKeepsKindAndCode(t *testing.T) {
+	var run models.IngestionRun
+	err := parsePlatformError("meta", 400, []byte(`{"error": {"message": "Session has expired", "code": 190, "error_subcode": 463}}`))
+	setRunError(&run, &RetryableError{Err: err})
+	if run.ErrorKind != "auth_expired" || run.ErrorCode != "190/463" || !strings.Contains(run.Error, "Session has expired") {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestMetaTransportErrorHidesAccessToken(t *testing.T) {
+	// A server that's gone makes the request fail before any response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	src := &MetaSource{AccessToken: "secret-token", AdAccountID: "act_1", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()}}
+	_, err := src.Fetch(context.Background(), testWindow)
+	if err == nil {
+		t.Fatal("Fetch succeeded against a closed server")
+	}
+	var run models.IngestionRun
+	setRunError(&run, err)
+	if strings.Contains(run.Error, "access_token=") || strings.Contains(run.Error, "secret-token") {
+		t.Errorf("run error leaks the token: %s", run.Error)
+	}
+}
@@ -3,92 +3,169 @@ package ingestion
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
+	"campaign-analytics/fx"
 	"campaign-analytics/models"
-	"campaign-analytics/processor"
 )
 
-// FetchGoogleCampaigns pulls campaign insights from Google Ads API via REST
-func FetchGoogleCampaigns() {
-	fmt.Println("[GOOGLE] Fetching campaign data from Google Ads API (REST)...")
+const (
+	googleDefaultBaseURL = "https://googleads.googleapis.com/v16"
+	googleTokenURL       = "https://oauth2.googleapis.com/token"
+)
+
+// GoogleSource pulls campaign insights from Google Ads API via REST
+type GoogleSource struct {
+	Tokens          TokenProvider
+	DeveloperToken  string
+	LoginCustomerID string // manager account ID when accessing a client account
+	CustomerID      string
+	BaseURL         string
+	Client          *APIClient
+	Paging          Paging
+	Location        *time.Location // reporting timezone; nil means UTC
+	Currency        string         // account currency, used when the API doesn't report one
+}
 
-	accessToken := os.Getenv("GOOGLE_ADS_ACCESS_TOKEN")
-	customerID := os.Getenv("GOOGLE_ADS_CUSTOMER_ID")
+func init() {
+	RegisterSource("google", NewGoogleSource)
+}
 
-	if accessToken == "" || customerID == "" {
-		fmt.Println("[GOOGLE] Missing Google Ads API credentials.")
-		return
+// NewGoogleSource builds a GoogleSource for one configured account
+func NewGoogleSource(acct Account) (Source, error) {
+	loc, err := acct.Location()
+	if err != nil {
+		return nil, err
 	}
+	return &GoogleSource{
+		Tokens:          tokenProviderFor(acct, googleTokenURL, TokenFormatOAuth2),
+		DeveloperToken:  acct.Credential("developer_token"),
+		LoginCustomerID: acct.Credential("login_customer_id"),
+		CustomerID:      acct.AccountID,
+		BaseURL:         googleDefaultBaseURL,
+		Client:          NewAPIClient("google"),
+		Paging:          PagingFromEnv(),
+		Location:        loc,
+		Currency:        acct.CurrencyCode(),
+	}, nil
+}
+
+// Name returns the source name used in ENABLED_SOURCES
+func (s *GoogleSource) Name() string { return "google" }
 
-	url := fmt.Sprintf("https://googleads.googleapis.com/v16/customers/%s/googleAds:search", customerID)
+// Fetch pulls campaign-level metrics for the given window, segmented by day.
+// It follows nextPageToken until the last page or the MaxPages cap.
+func (s *GoogleSource) Fetch(ctx context.Context, window Window) ([]models.CampaignMetrics, error) {
+	fmt.Println("[GOOGLE] Fetching campaign data from Google Ads API (REST)...")
 
-	query := map[string]interface{}{
-		"query": `SELECT campaign.id, campaign.name, metrics.impressions, metrics.clicks, metrics.cost_micros FROM campaign WHERE campaign.status = 'ENABLED' LIMIT 10`,
+	if s.Tokens == nil || s.CustomerID == "" {
+		return nil, fmt.Errorf("google: missing API credentials")
 	}
-	payload, _ := json.Marshal(query)
+	paging := s.Paging.withDefaults()
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
+	endpoint := fmt.Sprintf("%s/customers/%s/googleAds:search", s.BaseURL, s.CustomerID)
+	query := fmt.Sprintf(`SELECT campaign.id, campaign.name, customer.currency_code, segments.date, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions, metrics.conversions_value FROM campaign WHERE campaign.status = 'ENABLED' AND segments.date BETWEEN '%s' AND '%s'`,
+		window.From.Format("2006-01-02"), window.To.Format("2006-01-02"))
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("[GOOGLE] Request failed: %v\n", err)
-		return
-	}
-	defer resp.Body.Close()
+	var metrics []models.CampaignMetrics
+	pageToken := ""
+	for page := 0; ; page++ {
+		if page == paging.MaxPages {
+			warnPageCap("GOOGLE", paging)
+			break
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[GOOGLE] API returned non-200: %d\n", resp.StatusCode)
-		return
-	}
+		body := map[string]interface{}{
+			"query":    query,
+			"pageSize": paging.PageSize,
+		}
+		if pageToken != "" {
+			body["pageToken"] = pageToken
+		}
+		payload, _ := json.Marshal(body)
 
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	var response struct {
-		Results []struct {
-			Campaign struct {
-				Id   string `json:"id"`
-				Name string `json:"name"`
-			} `json:"campaign"`
-			Metrics struct {
-				Impressions string `json:"impressions"`
-				Clicks      string `json:"clicks"`
-				CostMicros  string `json:"costMicros"`
-			} `json:"metrics"`
-		} `json:"results"`
-	}
+		build := func(token string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payload))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			if s.DeveloperToken != "" {
+				req.Header.Set("developer-token", s.DeveloperToken)
+			}
+			if s.LoginCustomerID != "" {
+				req.Header.Set("login-customer-id", s.LoginCustomerID)
+			}
+			return req, nil
+		}
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		fmt.Printf("[GOOGLE] Failed to parse response: %v\n", err)
-		return
-	}
+		var response struct {
+			Results []struct {
+				Campaign struct {
+					Id   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"campaign"`
+				Customer struct {
+					CurrencyCode string `json:"currencyCode"`
+				} `json:"customer"`
+				Segments struct {
+					Date string `json:"date"`
+				} `json:"segments"`
+				Metrics struct {
+					Impressions      string        `json:"impressions"`
+					Clicks           string        `json:"clicks"`
+					CostMicros       string        `json:"costMicros"`
+					Conversions      flexFloat     `json:"conversions"`
+					ConversionsValue models.Micros `json:"conversionsValue"`
+				} `json:"metrics"`
+			} `json:"results"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := doAuthorizedJSON(ctx, s.Client, s.Tokens, build, &response); err != nil {
+			return nil, fmt.Errorf("google: %w", err)
+		}
+
+		for _, row := range response.Results {
+			impressions := atoi(row.Metrics.Impressions)
+			clicks := atoi(row.Metrics.Clicks)
+			costMicros, _ := strconv.ParseInt(row.Metrics.CostMicros, 10, 64)
+			date, timestamp, err := reportingDay(row.Segments.Date, s.Location)
+			if err != nil {
+				return nil, fmt.Errorf("google: %w", err)
+			}
+			currency := s.Currency
+			if row.Customer.CurrencyCode != "" {
+				currency = fx.NormalizeCode(row.Customer.CurrencyCode)
+			}
+
+			metrics = append(metrics, models.CampaignMetrics{
+				CampaignID:  fmt.Sprintf("g-%s", row.Campaign.Id),
+				Platform:    "Google",
+				AccountID:   s.CustomerID,
+				Impressions: impressions,
+				Clicks:      clicks,
+				Conversions: int(math.Round(float64(row.Metrics.Conversions))),
+				Cost:        models.Micros(costMicros),
+				Revenue:     row.Metrics.ConversionsValue,
+				Currency:    currency,
+				Timestamp:   timestamp,
+				Date:        date,
+			})
+		}
 
-	for _, row := range response.Results {
-		impressions := atoi(row.Metrics.Impressions)
-		clicks := atoi(row.Metrics.Clicks)
-		costMicros := atoi(row.Metrics.CostMicros)
-		cost := float64(costMicros) / 1_000_000
-
-		metric := models.CampaignMetrics{
-			CampaignID:  fmt.Sprintf("g-%s", row.Campaign.Id),
-			Platform:    "Google",
-			Impressions: impressions,
-			Clicks:      clicks,
-			Conversions: 0,
-			Cost:        cost,
-			Revenue:     0.0,
-			Timestamp:   time.Now().UTC().String(),
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
 		}
-		processor.ProcessMetric(metric)
 	}
+	return metrics, nil
 }
 
 // atoi safely parses string to int
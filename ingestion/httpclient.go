@@ -0,0 +1,358 @@
+// ingestion/httpclient.go
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"campaign-analytics/config"
+)
+
+const (
+	defaultMaxRetries = 4
+	defaultBaseDelay  = time.Second
+	defaultMaxDelay   = 60 * time.Second
+)
+
+// RetryableError marks a failure worth retrying (throttling, 5xx, timeouts).
+// RetryAfter is the minimum wait the platform asked for, if any.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// IsRetryable reports whether err is a transient failure
+func IsRetryable(err error) bool {
+	var r *RetryableError
+	return errors.As(err, &r)
+}
+
+// APIClient is the HTTP layer shared by all connectors. It rate limits
+// requests per platform, retries transient failures with exponential backoff
+// and jitter, and honours platform throttling signals.
+type APIClient struct {
+	Platform   string
+	HTTP       *http.Client
+	Limiter    *RateLimiter
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// Inspect runs on every response before the status check and can turn a
+	// platform-specific signal (e.g. an error code in a 200 body) into an error
+	Inspect func(resp *http.Response, body []byte) error
+}
+
+// NewAPIClient returns the default client for a platform, sharing that
+// platform's rate limiter with every other account on it
+func NewAPIClient(platform string) *APIClient {
+	c := &APIClient{
+		Platform:   platform,
+		HTTP:       &http.Client{Timeout: 30 * time.Second},
+		Limiter:    platformLimiter(platform),
+		MaxRetries: config.Int("INGESTION_MAX_RETRIES", defaultMaxRetries, 0),
+		BaseDelay:  defaultBaseDelay,
+		MaxDelay:   defaultMaxDelay,
+	}
+	switch platform {
+	case "meta":
+		c.Inspect = c.inspectMetaUsage
+	case "tiktok":
+		c.Inspect = inspectTiktokCode
+	}
+	return c
+}
+
+// DoJSON sends the request produced by build and decodes a successful body
+// into out, retrying retryable failures. build is called once per attempt so
+// request bodies can be replayed.
+func (c *APIClient) DoJSON(ctx context.Context, build func() (*http.Request, error), out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := c.doOnce(ctx, build, out)
+		if err == nil || !IsRetryable(err) || attempt >= c.MaxRetries {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		var r *RetryableError
+		if errors.As(err, &r) && r.RetryAfter > wait {
+			wait = r.RetryAfter
+		}
+		fmt.Printf("[HTTP] Retrying in %s (attempt %d/%d): %v\n", wait.Round(time.Millisecond), attempt+1, c.MaxRetries, err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *APIClient) doOnce(ctx context.Context, build func() (*http.Request, error), out interface{}) error {
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	req, err := build()
+	if err != nil {
+		return err
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var netErr net.Error
+		retryable := errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+		err = fmt.Errorf("request failed: %w", withoutURL(err))
+		if retryable {
+			return &RetryableError{Err: err}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &RetryableError{Err: fmt.Errorf("failed to read response: %w", err)}
+	}
+
+	if c.Inspect != nil {
+		if err := c.Inspect(resp, body); err != nil {
+			return err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := parsePlatformError(c.Platform, resp.StatusCode, body)
+		if isRetryableKind(apiErr.Kind) {
+			return &RetryableError{Err: apiErr, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// withoutURL strips the request URL from a transport error. Query strings can
+// carry credentials (Meta's access_token, also echoed in paging.next), and the
+// error text ends up in the run log and the /ingestion API.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// backoff returns the exponential delay for attempt with jitter in [d/2, d]
+func (c *APIClient) backoff(attempt int) time.Duration {
+	base, max := c.BaseDelay, c.MaxDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	if max <= 0 {
+		max = defaultMaxDelay
+	}
+	d := base << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// parseRetryAfter understands both delta-seconds and HTTP-date values
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// metaUsageThreshold is the usage percentage at which Meta calls are paused
+const metaUsageThreshold = 95
+
+// inspectMetaUsage reads X-Business-Use-Case-Usage and pauses the platform
+// limiter when Meta reports an account is about to be, or already is, throttled
+func (c *APIClient) inspectMetaUsage(resp *http.Response, body []byte) error {
+	header := resp.Header.Get("X-Business-Use-Case-Usage")
+	if header == "" {
+		return nil
+	}
+	var usage map[string][]struct {
+		CallCount                   int `json:"call_count"`
+		TotalCPUTime                int `json:"total_cputime"`
+		TotalTime                   int `json:"total_time"`
+		EstimatedTimeToRegainAccess int `json:"estimated_time_to_regain_access"`
+	}
+	if err := json.Unmarshal([]byte(header), &usage); err != nil {
+		return nil
+	}
+
+	var pause time.Duration
+	for _, entries := range usage {
+		for _, u := range entries {
+			if regain := time.Duration(u.EstimatedTimeToRegainAccess) * time.Minute; regain > pause {
+				pause = regain
+			}
+			if u.CallCount >= metaUsageThreshold || u.TotalCPUTime >= metaUsageThreshold || u.TotalTime >= metaUsageThreshold {
+				if pause < time.Minute {
+					pause = time.Minute
+				}
+			}
+		}
+	}
+	if pause > 0 && c.Limiter != nil {
+		fmt.Printf("[META] Business use case usage high, pausing requests for %s\n", pause)
+		c.Limiter.PauseFor(pause)
+	}
+	return nil
+}
+
+// inspectTiktokCode turns a non-zero TikTok "code" in a 200 body into a
+// PlatformError; TikTok reports auth and quota failures this way
+func inspectTiktokCode(resp *http.Response, body []byte) error {
+	var envelope struct {
+		Code *int `json:"code"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(body, &envelope) != nil || envelope.Code == nil || *envelope.Code == 0 {
+		return nil
+	}
+	apiErr := parsePlatformError("tiktok", resp.StatusCode, body)
+	if isRetryableKind(apiErr.Kind) {
+		return &RetryableError{Err: apiErr}
+	}
+	return apiErr
+}
+
+// RateLimiter is a token bucket shared by every caller of one platform.
+// PauseFor blocks all callers until a platform-requested cool-down ends.
+type RateLimiter struct {
+	mu          sync.Mutex
+	rate        float64 // tokens per second
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter allows rate requests per second with bursts of up to burst
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		if now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+		} else {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if l.tokens > l.burst {
+				l.tokens = l.burst
+			}
+			l.last = now
+			if l.tokens >= 1 {
+				l.tokens--
+				l.mu.Unlock()
+				return nil
+			}
+			wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// PauseFor stops handing out tokens for d
+func (l *RateLimiter) PauseFor(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// defaultPlatformRates are conservative requests/second per platform,
+// overridable with RATE_LIMIT_<PLATFORM>
+var defaultPlatformRates = map[string]float64{
+	"meta":     5,
+	"google":   10,
+	"tiktok":   10,
+	"linkedin": 2,
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*RateLimiter)
+)
+
+// platformLimiter returns the shared limiter for a platform
+func platformLimiter(platform string) *RateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[platform]; ok {
+		return l
+	}
+	rate, ok := defaultPlatformRates[platform]
+	if !ok {
+		rate = 5
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT_"+strings.ToUpper(platform)), 64); err == nil && v > 0 {
+		rate = v
+	}
+	l := NewRateLimiter(rate, int(rate)+1)
+	limiters[platform] = l
+	return l
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIClientRetriesTransientFailures(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+
+	c := &APIClient{HTTP: srv.Client(), MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	var out struct{ OK bool }
+	err := c.DoJSON(context.Background(), func() (*http.Request, error) {
+		return http.NewRequest("GET", srv.URL, nil)
+	}, &out)
+	if err != nil || !out.OK || calls != 3 {
+		t.Fatalf("err = %v, ok = %v, calls = %d; want success on third call", err, out.OK, calls)
+	}
+}
+
+func TestAPIClientDoesNotRetryTiktokAuthCode(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"code": 40001, "message": "Access token is invalid", "data": {}}`))
+	}))
+	defer srv.Close()
+
+	c := &APIClient{HTTP: srv.Client(), MaxRetries: 3, BaseDelay: time.Millisecond, Inspect: inspectTiktokCode}
+	var out struct{}
+	err := c.DoJSON(context.Background(), func() (*http.Request, error) {
+		return http.NewRequest("POST", srv.URL, nil)
+	}, &out)
+	if err == nil || IsRetryable(err) || calls != 1 {
+		t.Fatalf("err = %v, calls = %d; want one fatal error", err, calls)
+	}
+}
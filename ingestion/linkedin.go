@@ -2,85 +2,157 @@
 package ingestion
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"campaign-analytics/models"
-	"campaign-analytics/processor"
 )
 
-// FetchLinkedInCampaigns pulls campaign insights from LinkedIn Marketing API
-func FetchLinkedInCampaigns() {
-	fmt.Println("[LINKEDIN] Fetching campaign data from LinkedIn Marketing API...")
-
-	token := os.Getenv("LINKEDIN_ACCESS_TOKEN")
-	accountID := os.Getenv("LINKEDIN_ACCOUNT_ID")
-
-	if token == "" || accountID == "" {
-		fmt.Println("[LINKEDIN] Missing LinkedIn API credentials.")
-		return
-	}
+const (
+	linkedinDefaultBaseURL = "https://api.linkedin.com/v2"
+	linkedinTokenURL       = "https://www.linkedin.com/oauth/v2/accessToken"
+)
 
-	// start := time.Now().AddDate(0, 0, -7).Unix() * 1000 // 7 days ago in ms
-	// end := time.Now().Unix() * 1000                     // now in ms
+// linkedinFields are the analytics fields requested for every row
+const linkedinFields = "pivotValue,pivotValues,dateRange,impressions,clicks,costInLocalCurrency,externalWebsiteConversions,conversionValueInLocalCurrency"
 
-	url := fmt.Sprintf("https://api.linkedin.com/v2/adAnalyticsV2?q=analytics&dateRange.start.day=1&dateRange.start.month=1&dateRange.start.year=2024&dateRange.end.day=30&dateRange.end.month=4&dateRange.end.year=2024&pivot=CAMPAIGN&accounts=urn:li:sponsoredAccount:%s", accountID)
+// LinkedInSource pulls campaign insights from LinkedIn Marketing API
+type LinkedInSource struct {
+	Tokens    TokenProvider
+	AccountID string
+	BaseURL   string
+	Client    *APIClient
+	Paging    Paging
+	Location  *time.Location // reporting timezone; nil means UTC
+	Currency  string         // account currency the report amounts are in
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
+func init() {
+	RegisterSource("linkedin", NewLinkedInSource)
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+// NewLinkedInSource builds a LinkedInSource for one configured account
+func NewLinkedInSource(acct Account) (Source, error) {
+	loc, err := acct.Location()
 	if err != nil {
-		fmt.Printf("[LINKEDIN] Request failed: %v\n", err)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &LinkedInSource{
+		Tokens:    tokenProviderFor(acct, linkedinTokenURL, TokenFormatOAuth2),
+		AccountID: acct.AccountID,
+		BaseURL:   linkedinDefaultBaseURL,
+		Client:    NewAPIClient("linkedin"),
+		Paging:    PagingFromEnv(),
+		Location:  loc,
+		Currency:  acct.CurrencyCode(),
+	}, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[LINKEDIN] API returned non-200: %d\n", resp.StatusCode)
-		return
-	}
+// Name returns the source name used in ENABLED_SOURCES
+func (s *LinkedInSource) Name() string { return "linkedin" }
 
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	var response struct {
-		elements []struct {
-			CampaignURN         string  `json:"campaign"`
-			Impressions         int     `json:"impressions"`
-			Clicks              int     `json:"clicks"`
-			CostInLocalCurrency float64 `json:"costInLocalCurrency"`
-		} `json:"elements"`
-	}
+// Fetch pulls campaign-level analytics for the given window at daily granularity.
+// It advances start by count until paging.total is reached or the MaxPages cap.
+func (s *LinkedInSource) Fetch(ctx context.Context, window Window) ([]models.CampaignMetrics, error) {
+	fmt.Println("[LINKEDIN] Fetching campaign data from LinkedIn Marketing API...")
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		fmt.Printf("[LINKEDIN] Failed to parse response: %v\n", err)
-		return
+	if s.Tokens == nil || s.AccountID == "" {
+		return nil, fmt.Errorf("linkedin: missing API credentials")
 	}
+	paging := s.Paging.withDefaults()
+
+	from, to := window.From, window.To
+	baseURL := fmt.Sprintf("%s/adAnalyticsV2?q=analytics&dateRange.start.day=%d&dateRange.start.month=%d&dateRange.start.year=%d&dateRange.end.day=%d&dateRange.end.month=%d&dateRange.end.year=%d&timeGranularity=DAILY&pivot=CAMPAIGN&fields=%s&accounts=urn:li:sponsoredAccount:%s",
+		s.BaseURL, from.Day(), int(from.Month()), from.Year(), to.Day(), int(to.Month()), to.Year(), linkedinFields, s.AccountID)
 
-	for _, item := range response.elements {
-		// Campaign ID from URN: urn:li:sponsoredCampaign:<campaign_id>
-		parts := strings.Split(item.CampaignURN, ":")
-		campaignID := "l-unknown"
-		if len(parts) == 4 {
-			campaignID = "l-" + parts[3]
+	var metrics []models.CampaignMetrics
+	start := 0
+	for page := 0; ; page++ {
+		if page == paging.MaxPages {
+			warnPageCap("LINKEDIN", paging)
+			break
 		}
 
-		metric := models.CampaignMetrics{
-			CampaignID:  campaignID,
-			Platform:    "LinkedIn",
-			Impressions: item.Impressions,
-			Clicks:      item.Clicks,
-			Conversions: 0,
-			Cost:        item.CostInLocalCurrency,
-			Revenue:     0.0,
-			Timestamp:   time.Now().UTC().String(),
+		endpoint := fmt.Sprintf("%s&start=%d&count=%d", baseURL, start, paging.PageSize)
+		build := func(token string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
 		}
-		processor.ProcessMetric(metric)
+
+		var response struct {
+			Elements []struct {
+				PivotValue  string   `json:"pivotValue"`
+				PivotValues []string `json:"pivotValues"`
+				DateRange   struct {
+					Start struct {
+						Year  int `json:"year"`
+						Month int `json:"month"`
+						Day   int `json:"day"`
+					} `json:"start"`
+				} `json:"dateRange"`
+				Impressions                    int           `json:"impressions"`
+				Clicks                         int           `json:"clicks"`
+				CostInLocalCurrency            models.Micros `json:"costInLocalCurrency"`
+				ExternalWebsiteConversions     int           `json:"externalWebsiteConversions"`
+				ConversionValueInLocalCurrency models.Micros `json:"conversionValueInLocalCurrency"`
+			} `json:"elements"`
+			Paging struct {
+				Start int `json:"start"`
+				Count int `json:"count"`
+				Total int `json:"total"`
+			} `json:"paging"`
+		}
+		if err := doAuthorizedJSON(ctx, s.Client, s.Tokens, build, &response); err != nil {
+			return nil, fmt.Errorf("linkedin: %w", err)
+		}
+
+		for _, item := range response.Elements {
+			urn := item.PivotValue
+			if len(item.PivotValues) > 0 {
+				urn = item.PivotValues[0]
+			}
+			day := item.DateRange.Start
+			date, timestamp, err := reportingDay(fmt.Sprintf("%04d-%02d-%02d", day.Year, day.Month, day.Day), s.Location)
+			if err != nil {
+				return nil, fmt.Errorf("linkedin: %w", err)
+			}
+
+			metrics = append(metrics, models.CampaignMetrics{
+				CampaignID:  linkedinCampaignID(urn),
+				Platform:    "LinkedIn",
+				AccountID:   s.AccountID,
+				Impressions: item.Impressions,
+				Clicks:      item.Clicks,
+				Conversions: item.ExternalWebsiteConversions,
+				Cost:        item.CostInLocalCurrency,
+				Revenue:     item.ConversionValueInLocalCurrency,
+				Currency:    s.Currency,
+				Timestamp:   timestamp,
+				Date:        date,
+			})
+		}
+
+		start += len(response.Elements)
+		if len(response.Elements) < paging.PageSize || (response.Paging.Total > 0 && start >= response.Paging.Total) {
+			break
+		}
+	}
+	return metrics, nil
+}
+
+// linkedinCampaignID extracts the campaign ID from URN: urn:li:sponsoredCampaign:<campaign_id>
+func linkedinCampaignID(urn string) string {
+	parts := strings.Split(urn, ":")
+	if len(parts) == 4 {
+		return "l-" + parts[3]
 	}
+	return "l-unknown"
 }
@@ -2,79 +2,155 @@
 package ingestion
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"math"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
 	"time"
 
+	"campaign-analytics/fx"
 	"campaign-analytics/models"
-	"campaign-analytics/processor"
 )
 
-// FetchMetaCampaigns pulls campaign insights from Meta Ads API
-func FetchMetaCampaigns() {
-	fmt.Println("[META] Fetching campaign data from Meta Ads API...")
-
-	token := os.Getenv("META_ACCESS_TOKEN")
-	adAccountID := os.Getenv("META_AD_ACCOUNT_ID")
+const metaDefaultBaseURL = "https://graph.facebook.com/v18.0"
 
-	if token == "" || adAccountID == "" {
-		fmt.Println("[META] Missing Meta API credentials.")
-		return
-	}
+// MetaSource pulls campaign insights from Meta Ads API
+type MetaSource struct {
+	AccessToken string
+	AdAccountID string
+	BaseURL     string
+	Client      *APIClient
+	Paging      Paging
+	Location    *time.Location // reporting timezone; nil means UTC
+	Currency    string         // account currency, used when the API doesn't report one
 
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/%s/insights?fields=campaign_name,impressions,clicks,spend,date_stop&level=campaign&date_preset=last_30d&access_token=%s", adAccountID, token)
+	// ConversionActionTypes lists the action types counted as conversions
+	ConversionActionTypes []string
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	client := &http.Client{Timeout: 10 * time.Second}
+func init() {
+	RegisterSource("meta", NewMetaSource)
+}
 
-	resp, err := client.Do(req)
+// NewMetaSource builds a MetaSource for one configured account
+func NewMetaSource(acct Account) (Source, error) {
+	loc, err := acct.Location()
 	if err != nil {
-		fmt.Printf("[META] Request failed: %v\n", err)
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &MetaSource{
+		AccessToken: acct.Credential("access_token"),
+		AdAccountID: acct.AccountID,
+		BaseURL:     metaDefaultBaseURL,
+		Client:      NewAPIClient("meta"),
+		Paging:      PagingFromEnv(),
+		Location:    loc,
+		Currency:    acct.CurrencyCode(),
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[META] API returned non-200: %d\n", resp.StatusCode)
-		return
-	}
+		ConversionActionTypes: metaConversionActionTypesFromEnv(),
+	}, nil
+}
 
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	var response struct {
-		Data []struct {
-			CampaignName string `json:"campaign_name"`
-			Impressions  string `json:"impressions"`
-			Clicks       string `json:"clicks"`
-			Spend        string `json:"spend"`
-			DateStop     string `json:"date_stop"`
-		} `json:"data"`
-	}
+// Name returns the source name used in ENABLED_SOURCES
+func (s *MetaSource) Name() string { return "meta" }
+
+// Fetch pulls campaign-level insights for the given window, one row per day.
+// It follows paging.next until the last page or the MaxPages cap.
+func (s *MetaSource) Fetch(ctx context.Context, window Window) ([]models.CampaignMetrics, error) {
+	fmt.Println("[META] Fetching campaign data from Meta Ads API...")
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		fmt.Printf("[META] Failed to parse response: %v\n", err)
-		return
+	if s.AccessToken == "" || s.AdAccountID == "" {
+		return nil, fmt.Errorf("meta: missing API credentials")
 	}
+	paging := s.Paging.withDefaults()
+
+	timeRange, _ := json.Marshal(map[string]string{
+		"since": window.From.Format("2006-01-02"),
+		"until": window.To.Format("2006-01-02"),
+	})
+	params := url.Values{}
+	params.Set("fields", "campaign_id,impressions,clicks,spend,actions,action_values,date_start,account_currency")
+	params.Set("level", "campaign")
+	params.Set("time_range", string(timeRange))
+	params.Set("time_increment", "1")
+	params.Set("limit", strconv.Itoa(paging.PageSize))
+	params.Set("access_token", s.AccessToken)
+
+	next := fmt.Sprintf("%s/%s/insights?%s", s.BaseURL, s.AdAccountID, params.Encode())
+
+	var metrics []models.CampaignMetrics
+	for page := 0; next != ""; page++ {
+		if page == paging.MaxPages {
+			warnPageCap("META", paging)
+			break
+		}
+
+		build := func() (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, "GET", next, nil)
+		}
+
+		var response struct {
+			Data []struct {
+				CampaignID   string       `json:"campaign_id"`
+				Impressions  string       `json:"impressions"`
+				Clicks       string       `json:"clicks"`
+				Spend        string       `json:"spend"`
+				Actions      []metaAction `json:"actions"`
+				ActionValues []metaAction `json:"action_values"`
+				DateStart    string       `json:"date_start"`
+				Currency     string       `json:"account_currency"`
+			} `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := s.Client.DoJSON(ctx, build, &response); err != nil {
+			return nil, fmt.Errorf("meta: %w", err)
+		}
+
+		for _, item := range response.Data {
+			impressions := atoi(item.Impressions)
+			clicks := atoi(item.Clicks)
+			// A malformed amount fails the fetch rather than being stored as 0
+			spend, err := models.ParseMicros(item.Spend)
+			if err != nil {
+				return nil, fmt.Errorf("meta: campaign %s spend: %w", item.CampaignID, err)
+			}
+			conversions, err := sumMetaActions(item.Actions, s.ConversionActionTypes)
+			if err != nil {
+				return nil, fmt.Errorf("meta: campaign %s actions: %w", item.CampaignID, err)
+			}
+			revenue, err := sumMetaActions(item.ActionValues, s.ConversionActionTypes)
+			if err != nil {
+				return nil, fmt.Errorf("meta: campaign %s action_values: %w", item.CampaignID, err)
+			}
+			date, timestamp, err := reportingDay(item.DateStart, s.Location)
+			if err != nil {
+				return nil, fmt.Errorf("meta: %w", err)
+			}
+			currency := s.Currency
+			if item.Currency != "" {
+				currency = fx.NormalizeCode(item.Currency)
+			}
 
-	for _, item := range response.Data {
-		impressions := atoi(item.Impressions)
-		clicks := atoi(item.Clicks)
-		spend, _ := strconv.ParseFloat(item.Spend, 64)
-		timestamp, _ := time.Parse("2006-01-02", item.DateStop)
-
-		metric := models.CampaignMetrics{
-			CampaignID:  fmt.Sprintf("m-%s", item.CampaignName),
-			Platform:    "Meta",
-			Impressions: impressions,
-			Clicks:      clicks,
-			Conversions: 0,
-			Cost:        spend,
-			Revenue:     0.0,
-			Timestamp:   timestamp.UTC().String(),
+			metrics = append(metrics, models.CampaignMetrics{
+				CampaignID:  fmt.Sprintf("m-%s", item.CampaignID), // names aren't unique
+				Platform:    "Meta",
+				AccountID:   s.AdAccountID,
+				Impressions: impressions,
+				Clicks:      clicks,
+				Conversions: int(math.Round(conversions.Float64())),
+				Cost:        spend,
+				Revenue:     revenue,
+				Currency:    currency,
+				Timestamp:   timestamp,
+				Date:        date,
+			})
 		}
-		processor.ProcessMetric(metric)
+		next = response.Paging.Next
 	}
+	return metrics, nil
 }
@@ -0,0 +1,204 @@
+// ingestion/oauth.go
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenProvider supplies bearer tokens for platform API calls
+type TokenProvider interface {
+	// Token returns a valid access token, refreshing it if needed
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops any cached token, e.g. after the API answered 401
+	Invalidate()
+}
+
+// StaticToken is a fixed access token that is never refreshed
+type StaticToken string
+
+// Token returns the fixed token
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	if t == "" {
+		return "", errors.New("no access token configured")
+	}
+	return string(t), nil
+}
+
+// Invalidate is a no-op; a static token cannot be refreshed
+func (t StaticToken) Invalidate() {}
+
+// Token endpoint formats understood by RefreshTokenProvider
+const (
+	// TokenFormatOAuth2 is the standard form-encoded refresh_token grant (Google, LinkedIn)
+	TokenFormatOAuth2 = "oauth2"
+	// TokenFormatTiktok is TikTok's JSON body with app_id/secret and a wrapped response
+	TokenFormatTiktok = "tiktok"
+)
+
+// tokenExpiryMargin refreshes tokens slightly before they actually expire
+const tokenExpiryMargin = time.Minute
+
+// RefreshTokenProvider exchanges a refresh token for access tokens and caches
+// each one until shortly before it expires
+type RefreshTokenProvider struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	Format       string
+	Client       *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns the cached access token or fetches a new one
+func (p *RefreshTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Now().Before(p.expiry) {
+		return p.token, nil
+	}
+
+	token, expiresIn, refreshToken, err := p.exchange(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token = token
+	p.expiry = time.Now().Add(expiresIn - tokenExpiryMargin)
+	if refreshToken != "" {
+		// LinkedIn and TikTok may rotate the refresh token on every exchange
+		p.RefreshToken = refreshToken
+	}
+	return p.token, nil
+}
+
+// Invalidate forces the next Token call to refresh
+func (p *RefreshTokenProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = ""
+}
+
+// exchange performs the refresh_token grant against TokenURL
+func (p *RefreshTokenProvider) exchange(ctx context.Context) (string, time.Duration, string, error) {
+	var req *http.Request
+	var err error
+	if p.Format == TokenFormatTiktok {
+		body, _ := json.Marshal(map[string]string{
+			"app_id":        p.ClientID,
+			"secret":        p.ClientSecret,
+			"refresh_token": p.RefreshToken,
+			"grant_type":    "refresh_token",
+		})
+		req, err = http.NewRequestWithContext(ctx, "POST", p.TokenURL, bytes.NewBuffer(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", p.RefreshToken)
+		form.Set("client_id", p.ClientID)
+		form.Set("client_secret", p.ClientSecret)
+		req, err = http.NewRequestWithContext(ctx, "POST", p.TokenURL, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, "", fmt.Errorf("token refresh returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	type tokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	var parsed struct {
+		tokenResponse
+		Code    int           `json:"code"`
+		Message string        `json:"message"`
+		Data    tokenResponse `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", 0, "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	tok := parsed.tokenResponse
+	if p.Format == TokenFormatTiktok {
+		if parsed.Code != 0 {
+			return "", 0, "", fmt.Errorf("token refresh failed: code %d: %s", parsed.Code, parsed.Message)
+		}
+		tok = parsed.Data
+	}
+	if tok.AccessToken == "" {
+		return "", 0, "", errors.New("token response has no access_token")
+	}
+
+	expiresIn := time.Duration(tok.ExpiresIn) * time.Second
+	if expiresIn <= tokenExpiryMargin {
+		expiresIn = tokenExpiryMargin + time.Minute
+	}
+	return tok.AccessToken, expiresIn, tok.RefreshToken, nil
+}
+
+// tokenProviderFor returns a RefreshTokenProvider when acct has a refresh token
+// and falls back to a static access token otherwise
+func tokenProviderFor(acct Account, tokenURL, format string) TokenProvider {
+	if refresh := acct.Credential("refresh_token"); refresh != "" {
+		return &RefreshTokenProvider{
+			TokenURL:     tokenURL,
+			ClientID:     acct.Credential("client_id"),
+			ClientSecret: acct.Credential("client_secret"),
+			RefreshToken: refresh,
+			Format:       format,
+		}
+	}
+	return StaticToken(acct.Credential("access_token"))
+}
+
+// doAuthorizedJSON builds a request with a bearer token from tokens and decodes
+// the response into out. When the platform reports an expired or invalid token
+// (HTTP 401, or TikTok's auth codes) the token is invalidated and the call retried once.
+func doAuthorizedJSON(ctx context.Context, client *APIClient, tokens TokenProvider, build func(token string) (*http.Request, error), out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		token, err := tokens.Token(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = client.DoJSON(ctx, func() (*http.Request, error) { return build(token) }, out)
+		if attempt == 0 && errors.Is(err, ErrAuthExpired) {
+			tokens.Invalidate()
+			continue
+		}
+		return err
+	}
+}
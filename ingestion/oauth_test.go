@@ -0,0 +1,74 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeOAuthServer issues tok-1, tok-2, ... for every refresh_token grant
+func fakeOAuthServer(t *testing.T, exchanges *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-me" {
+			t.Errorf("unexpected token request: %v", r.Form)
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		*exchanges++
+		fmt.Fprintf(w, `{"access_token":"tok-%d","expires_in":3600,"token_type":"Bearer"}`, *exchanges)
+	}))
+}
+
+func TestRefreshTokenProviderCachesUntilInvalidated(t *testing.T) {
+	exchanges := 0
+	oauth := fakeOAuthServer(t, &exchanges)
+	defer oauth.Close()
+
+	p := &RefreshTokenProvider{TokenURL: oauth.URL, ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh-me"}
+	for i := 0; i < 3; i++ {
+		if tok, err := p.Token(context.Background()); err != nil || tok != "tok-1" {
+			t.Fatalf("Token() = %q, %v; want tok-1", tok, err)
+		}
+	}
+	p.Invalidate()
+	if tok, _ := p.Token(context.Background()); tok != "tok-2" || exchanges != 2 {
+		t.Fatalf("after Invalidate: token %q, exchanges %d; want tok-2 and 2", tok, exchanges)
+	}
+}
+
+func TestGoogleRefreshesTokenOn401(t *testing.T) {
+	exchanges := 0
+	oauth := fakeOAuthServer(t, &exchanges)
+	defer oauth.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("developer-token") != "dev" {
+			t.Errorf("developer-token header = %q", r.Header.Get("developer-token"))
+		}
+		// Pretend the first issued token was revoked before its expiry
+		if r.Header.Get("Authorization") != "Bearer tok-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		serveFixture(t, w, "", "google_page2.json")
+	}))
+	defer api.Close()
+
+	src := &GoogleSource{
+		Tokens:         &RefreshTokenProvider{TokenURL: oauth.URL, RefreshToken: "refresh-me"},
+		DeveloperToken: "dev",
+		CustomerID:     "123",
+		BaseURL:        api.URL,
+		Client:         &APIClient{HTTP: api.Client()},
+	}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || exchanges != 2 {
+		t.Fatalf("rows = %d, exchanges = %d; want 1 and 2", len(got), exchanges)
+	}
+}
@@ -0,0 +1,43 @@
+// ingestion/pagination.go
+package ingestion
+
+import (
+	"fmt"
+
+	"campaign-analytics/config"
+)
+
+const (
+	defaultPageSize = 500
+	defaultMaxPages = 100
+)
+
+// Paging controls how connectors walk multi-page API responses
+type Paging struct {
+	PageSize int // rows requested per page
+	MaxPages int // safety cap on pages fetched per call
+}
+
+// PagingFromEnv reads INGESTION_PAGE_SIZE and INGESTION_MAX_PAGES
+func PagingFromEnv() Paging {
+	return Paging{
+		PageSize: config.Int("INGESTION_PAGE_SIZE", defaultPageSize, 1),
+		MaxPages: config.Int("INGESTION_MAX_PAGES", defaultMaxPages, 1),
+	}
+}
+
+// withDefaults fills in zero values so a zero Paging is usable
+func (p Paging) withDefaults() Paging {
+	if p.PageSize <= 0 {
+		p.PageSize = defaultPageSize
+	}
+	if p.MaxPages <= 0 {
+		p.MaxPages = defaultMaxPages
+	}
+	return p
+}
+
+// warnPageCap logs that a connector stopped early because of MaxPages
+func warnPageCap(platform string, p Paging) {
+	fmt.Printf("[%s] Stopped after %d pages (INGESTION_MAX_PAGES); remaining rows were not fetched\n", platform, p.MaxPages)
+}
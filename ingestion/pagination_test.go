@@ -0,0 +1,221 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+var testWindow = Window{
+	From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	To:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+}
+
+// serveFixture writes testdata/<name> with {{server}} replaced by the server URL
+func serveFixture(t *testing.T, w http.ResponseWriter, serverURL, name string) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(strings.ReplaceAll(string(data), "{{server}}", serverURL)))
+}
+
+// campaignDays flattens rows to "campaign_id@day" for easy comparison
+func campaignDays(metrics []models.CampaignMetrics) []string {
+	var out []string
+	for _, m := range metrics {
+		out = append(out, m.CampaignID+"@"+m.Date)
+	}
+	return out
+}
+
+func assertRows(t *testing.T, got []models.CampaignMetrics, want ...string) {
+	t.Helper()
+	if strings.Join(campaignDays(got), ",") != strings.Join(want, ",") {
+		t.Fatalf("rows = %v, want %v", campaignDays(got), want)
+	}
+}
+
+func TestMetaFollowsPagingNext(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") == "c1" {
+			serveFixture(t, w, srv.URL, "meta_page2.json")
+			return
+		}
+		if got := r.URL.Query().Get("limit"); got != "2" {
+			t.Errorf("limit = %q, want 2", got)
+		}
+		serveFixture(t, w, srv.URL, "meta_page1.json")
+	}))
+	defer srv.Close()
+
+	src := &MetaSource{AccessToken: "tok", AdAccountID: "act_1", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()},
+		Paging: Paging{PageSize: 2}, ConversionActionTypes: []string{"purchase"}}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two campaigns both named "brand" stay separate rows
+	assertRows(t, got, "m-120001@2024-05-01", "m-120001@2024-05-02", "m-120002@2024-05-01", "m-120003@2024-05-01")
+	if got[0].Conversions != 3 || got[0].Revenue != 90*models.MicrosPerUnit {
+		t.Errorf("conversions/revenue = %d/%v, want 3/90", got[0].Conversions, got[0].Revenue)
+	}
+	// Attributed conversions can be fractional; they round like Google's
+	if got[1].Conversions != 3 {
+		t.Errorf("conversions from 2.9 actions = %d, want 3", got[1].Conversions)
+	}
+}
+
+func TestGoogleFollowsNextPageToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string `json:"query"`
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.Contains(body.Query, "LIMIT") {
+			t.Errorf("query should not be limited: %s", body.Query)
+		}
+		if body.PageToken == "token-2" {
+			serveFixture(t, w, "", "google_page2.json")
+			return
+		}
+		serveFixture(t, w, "", "google_page1.json")
+	}))
+	defer srv.Close()
+
+	src := &GoogleSource{Tokens: StaticToken("tok"), CustomerID: "123", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()}}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRows(t, got, "g-111@2024-05-01", "g-111@2024-05-02", "g-222@2024-05-01")
+	if got[0].Cost != 2_500_000 || got[0].Conversions != 2 {
+		t.Errorf("cost/conversions = %v/%d, want 2.5/2", got[0].Cost, got[0].Conversions)
+	}
+}
+
+func TestTiktokWalksPageInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Page int `json:"page"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Page == 2 {
+			serveFixture(t, w, "", "tiktok_page2.json")
+			return
+		}
+		serveFixture(t, w, "", "tiktok_page1.json")
+	}))
+	defer srv.Close()
+
+	src := &TiktokSource{Tokens: StaticToken("tok"), AdvertiserID: "adv", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()},
+		Paging: Paging{PageSize: 2}}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRows(t, got, "t-901@2024-05-01", "t-901@2024-05-02", "t-902@2024-05-01")
+}
+
+func TestLinkedInAdvancesStart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "2" {
+			serveFixture(t, w, "", "linkedin_page2.json")
+			return
+		}
+		serveFixture(t, w, "", "linkedin_page1.json")
+	}))
+	defer srv.Close()
+
+	src := &LinkedInSource{Tokens: StaticToken("tok"), AccountID: "42", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()},
+		Paging: Paging{PageSize: 2}}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertRows(t, got, "l-501@2024-05-01", "l-501@2024-05-02", "l-502@2024-05-01")
+	if got[0].Cost != 20*models.MicrosPerUnit || got[0].Revenue != 55*models.MicrosPerUnit {
+		t.Errorf("cost/revenue = %v/%v, want 20/55", got[0].Cost, got[0].Revenue)
+	}
+}
+
+func TestMaxPagesCapsRequests(t *testing.T) {
+	requests := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		serveFixture(t, w, srv.URL, "meta_page1.json") // always has a next page
+	}))
+	defer srv.Close()
+
+	src := &MetaSource{AccessToken: "tok", AdAccountID: "act_1", BaseURL: srv.URL, Client: &APIClient{HTTP: srv.Client()},
+		Paging: Paging{PageSize: 2, MaxPages: 3}}
+	got, err := src.Fetch(context.Background(), testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || len(got) != 6 {
+		t.Fatalf("requests = %d, rows = %d; want 3 and 6", requests, len(got))
+	}
+}
+
+func TestMalformedMoneyFailsFetch(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		src  func(url string, client *http.Client) Source
+		want string
+	}{
+		{
+			name: "meta spend",
+			body: `{"data": [{"campaign_id": "1", "spend": "12,50", "date_start": "2024-05-01"}]}`,
+			src: func(url string, client *http.Client) Source {
+				return &MetaSource{AccessToken: "tok", AdAccountID: "act_1", BaseURL: url, Client: &APIClient{HTTP: client}}
+			},
+			want: "spend",
+		},
+		{
+			name: "meta action value",
+			body: `{"data": [{"campaign_id": "1", "spend": "1", "date_start": "2024-05-01",
+				"action_values": [{"action_type": "purchase", "value": "n/a"}]}]}`,
+			src: func(url string, client *http.Client) Source {
+				return &MetaSource{AccessToken: "tok", AdAccountID: "act_1", BaseURL: url, Client: &APIClient{HTTP: client},
+					ConversionActionTypes: []string{"purchase"}}
+			},
+			want: "action_values",
+		},
+		{
+			name: "tiktok purchase value",
+			body: `{"code": 0, "data": {"list": [{"dimensions": {"campaign_id": "9", "stat_time_day": "2024-05-01 00:00:00"},
+				"metrics": {"spend": "1.00", "total_purchase_value": "1.2.3"}}], "page_info": {"page": 1, "total_page": 1}}}`,
+			src: func(url string, client *http.Client) Source {
+				return &TiktokSource{Tokens: StaticToken("tok"), AdvertiserID: "adv", BaseURL: url, Client: &APIClient{HTTP: client}}
+			},
+			want: "total_purchase_value",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			got, err := tc.src(srv.URL, srv.Client()).Fetch(context.Background(), testWindow)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("rows = %v, err = %v; want an error mentioning %s", campaignDays(got), err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// ingestion/runlog.go
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+// setRunError records err on the run, keeping the platform code and kind when available
+func setRunError(r *models.IngestionRun, err error) {
+	if err == nil {
+		return
+	}
+	r.Error = err.Error()
+	var apiErr *PlatformError
+	if errors.As(err, &apiErr) {
+		r.ErrorKind = apiErr.KindName()
+		r.ErrorCode = apiErr.Code
+	}
+}
+
+// countWrite tallies one processed row on the run
+func countWrite(r *models.IngestionRun, result storage.WriteResult, err error) {
+	switch {
+	case err != nil:
+		r.RowsFailed++
+	case result == storage.WriteInserted:
+		r.RowsInserted++
+	case result == storage.WriteUpdated:
+		r.RowsUpdated++
+	default:
+		r.RowsDeduped++
+	}
+}
+
+// RunRecorder persists ingestion runs
+type RunRecorder interface {
+	RecordRun(ctx context.Context, run models.IngestionRun) error
+}
+
+// DBRunLog stores runs in the ingestion_runs table
+type DBRunLog struct{}
+
+// RecordRun inserts one run into ingestion_runs
+func (DBRunLog) RecordRun(ctx context.Context, run models.IngestionRun) error {
+	_, err := storage.InsertIngestionRun(ctx, run)
+	return err
+}
+
+// FileRunLog appends runs as JSON lines to a local file
+type FileRunLog struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// RecordRun appends one run to the log file
+func (l *FileRunLog) RecordRun(ctx context.Context, run models.IngestionRun) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(run)
+}
+
+// RunLogs receive every run recorded by the dispatcher: always the
+// ingestion_runs table, plus a JSONL file when INGESTION_RUN_LOG is set
+var RunLogs = defaultRunLogs()
+
+func defaultRunLogs() []RunRecorder {
+	logs := []RunRecorder{DBRunLog{}}
+	if path := os.Getenv("INGESTION_RUN_LOG"); path != "" {
+		logs = append(logs, &FileRunLog{Path: path})
+	}
+	return logs
+}
+
+// recordRun logs a run summary and hands it to every RunLogs recorder
+func recordRun(ctx context.Context, run models.IngestionRun) {
+	took := run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond)
+	if run.Error != "" {
+		fmt.Printf("[DISPATCHER] %s/%s failed after %s: %s\n", run.Source, run.AccountID, took, run.Error)
+	} else {
+		fmt.Printf("[DISPATCHER] %s/%s fetched %d rows in %s (%d inserted, %d updated, %d deduped, %d failed)\n",
+			run.Source, run.AccountID, run.RowsFetched, took, run.RowsInserted, run.RowsUpdated, run.RowsDeduped, run.RowsFailed)
+	}
+	for _, log := range RunLogs {
+		if err := log.RecordRun(ctx, run); err != nil {
+			fmt.Printf("[DISPATCHER] Failed to record run: %v\n", err)
+		}
+	}
+}
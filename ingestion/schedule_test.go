@@ -0,0 +1,38 @@
+package ingestion
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, 4, 5, 10, 7, 30, 0, time.UTC) // a Friday
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"15m", from.Add(15 * time.Minute)},
+		{"@every 2h", from.Add(2 * time.Hour)},
+		{"*/15 * * * *", time.Date(2024, 4, 5, 10, 15, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2024, 4, 5, 12, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2024, 4, 8, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 4, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2024, 4, 7, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", c.spec, err)
+		}
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Errorf("%q: Next = %s, want %s", c.spec, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"", "soon", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want error", bad)
+		}
+	}
+}
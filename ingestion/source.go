@@ -0,0 +1,93 @@
+// ingestion/source.go
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+// Window is the reporting date range a Source is asked to fetch (inclusive)
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// LastNDays returns a window covering the n days up to and including now's day
+func LastNDays(now time.Time, n int) Window {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return Window{From: from.AddDate(0, 0, -(n - 1)), To: now}
+}
+
+// Source is a connector that pulls campaign metrics from one ad account.
+// Fetch only returns rows; persisting them is up to the caller.
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context, window Window) ([]models.CampaignMetrics, error)
+}
+
+// SourceFactory builds a Source for one configured account
+type SourceFactory func(acct Account) (Source, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]SourceFactory)
+)
+
+// RegisterSource makes a platform available to the dispatcher under name.
+// Registering the same name twice replaces the earlier factory.
+func RegisterSource(name string, factory SourceFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[normalizeSourceName(name)] = factory
+}
+
+// NewSource builds the Source for acct using its platform's registered factory
+func NewSource(acct Account) (Source, error) {
+	registryMu.RLock()
+	factory, ok := registry[normalizeSourceName(acct.Platform)]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q", acct.Platform)
+	}
+	return factory(acct)
+}
+
+// RegisteredSources returns the names of all registered platforms in sorted order
+func RegisteredSources() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reportingDay parses a platform reporting day (YYYY-MM-DD, optionally with a
+// trailing time) in the account's timezone. It returns the day itself, stored
+// as the row's date, and the instant that day starts, stored as its timestamp.
+// A nil loc means UTC.
+func reportingDay(day string, loc *time.Location) (string, time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if len(day) > len("2006-01-02") {
+		day = day[:len("2006-01-02")]
+	}
+	t, err := time.ParseInLocation("2006-01-02", day, loc)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid reporting day %q: %w", day, err)
+	}
+	return day, t, nil
+}
+
+func normalizeSourceName(name string) string {
+	return strings.TrimSpace(strings.ToLower(name))
+}
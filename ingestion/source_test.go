@@ -0,0 +1,39 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegisterSourceTwiceReplacesFactory(t *testing.T) {
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "fake")
+		registryMu.Unlock()
+	})
+	RegisterSource("fake", func(Account) (Source, error) { return nil, errors.New("first factory") })
+	// Names are normalised, so this registers the same platform again
+	RegisterSource(" Fake", func(Account) (Source, error) { return fakeSource{}, nil })
+
+	src, err := NewSource(Account{Platform: "FAKE"})
+	if err != nil || src == nil {
+		t.Fatalf("NewSource = %v, %v; want the second factory's source", src, err)
+	}
+	n := 0
+	for _, name := range RegisteredSources() {
+		if name == "fake" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("fake is listed %d times in %v, want once", n, RegisteredSources())
+	}
+}
+
+func TestLastNDaysCoversNDays(t *testing.T) {
+	w := LastNDays(time.Date(2024, 5, 7, 15, 30, 0, 0, time.UTC), 7)
+	if want := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC); !w.From.Equal(want) {
+		t.Errorf("From = %s, want %s (05-01..05-07 is 7 days)", w.From, want)
+	}
+}
@@ -1,11 +1,13 @@
 package ingestion
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
 	"time"
 
+	"campaign-analytics/fx"
 	"campaign-analytics/models"
 	"campaign-analytics/processor"
 )
@@ -13,28 +15,36 @@ import (
 // platforms is a mock set of ad platforms
 var platforms = []string{"Meta", "Google", "LinkedIn", "TikTok"}
 
-// SimulateStream generates random campaign metrics and sends them for processing
-func StartSimulator() {
-	go func() {
-		for {
-			metric := models.CampaignMetrics{
-				CampaignID:  fmt.Sprintf("cmp-%d", rand.Intn(100)),
-				Platform:    platforms[rand.Intn(len(platforms))],
-				Impressions: rand.Intn(1000),
-				Clicks:      rand.Intn(200),
-				Conversions: rand.Intn(50),
-				Cost:        float64(rand.Intn(5000)) / 100,
-				Revenue:     float64(rand.Intn(10000)) / 100,
-				Timestamp:   time.Now().Format(time.RFC3339),
-			}
+// StartSimulator generates random campaign metrics and sends them for processing
+// until ctx is cancelled
+func StartSimulator(ctx context.Context) {
+	for {
+		now := time.Now().UTC()
+		metric := models.CampaignMetrics{
+			CampaignID:  fmt.Sprintf("cmp-%d", rand.Intn(100)),
+			Platform:    platforms[rand.Intn(len(platforms))],
+			Impressions: rand.Intn(1000),
+			Clicks:      rand.Intn(200),
+			Conversions: rand.Intn(50),
+			Cost:        models.Micros(rand.Intn(5000)) * 10_000,
+			Revenue:     models.Micros(rand.Intn(10000)) * 10_000,
+			Timestamp:   now,
+			Date:        now.Format("2006-01-02"),
+		}
+		// Simulated amounts are already in the reporting currency
+		metric.Currency, metric.OriginalCost, metric.OriginalRevenue, metric.FXRate = fx.ReportingCurrency, metric.Cost, metric.Revenue, 1
 
-			data, _ := json.Marshal(metric)
-			fmt.Println("Ingested:", string(data))
+		data, _ := json.Marshal(metric)
+		fmt.Println("Ingested:", string(data))
 
-			// Send the metric to aggregator for processing
-			processor.ProcessMetric(metric)
+		// Queue the metric for the aggregator; a metric already generated is
+		// still written if shutdown starts meanwhile
+		processor.Submit(context.WithoutCancel(ctx), metric, nil)
 
-			time.Sleep(2 * time.Second) // simulate delay
+		// simulate delay
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			fmt.Println("[SIMULATOR] Stopped")
+			return
 		}
-	}()
+	}
 }
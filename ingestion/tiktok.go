@@ -3,88 +3,160 @@ package ingestion
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
 	"time"
 
 	"campaign-analytics/models"
-	"campaign-analytics/processor"
 )
 
-// FetchTiktokCampaigns pulls campaign insights from TikTok Ads API
-func FetchTiktokCampaigns() {
-	fmt.Println("[TIKTOK] Fetching campaign data from TikTok Marketing API...")
+const (
+	tiktokDefaultBaseURL = "https://business-api.tiktok.com/open_api/v1.3"
+	tiktokTokenURL       = tiktokDefaultBaseURL + "/oauth2/refresh_token/"
+)
 
-	token := os.Getenv("TIKTOK_ACCESS_TOKEN")
-	advertiserID := os.Getenv("TIKTOK_ADVERTISER_ID")
+// tiktokMaxPageSize is the largest page_size the reporting API accepts
+const tiktokMaxPageSize = 1000
 
-	if token == "" || advertiserID == "" {
-		fmt.Println("[TIKTOK] Missing TikTok API credentials.")
-		return
-	}
+// TiktokSource pulls campaign insights from TikTok Ads API
+type TiktokSource struct {
+	Tokens       TokenProvider
+	AdvertiserID string
+	BaseURL      string
+	Client       *APIClient
+	Paging       Paging
+	Location     *time.Location // reporting timezone; nil means UTC
+	Currency     string         // account currency the report amounts are in
+}
 
-	url := "https://business-api.tiktok.com/open_api/v1.3/report/integrated/get/"
+func init() {
+	RegisterSource("tiktok", NewTiktokSource)
+}
 
-	payload := map[string]interface{}{
-		"advertiser_id": advertiserID,
-		"report_type":   "BASIC",
-		"dimensions":    []string{"campaign_id"},
-		"metrics":       []string{"impressions", "clicks", "spend"},
-		"data_level":    "CAMPAIGN",
-		"start_date":    time.Now().AddDate(0, 0, -7).Format("2006-01-02"),
-		"end_date":      time.Now().Format("2006-01-02"),
+// NewTiktokSource builds a TiktokSource for one configured account
+func NewTiktokSource(acct Account) (Source, error) {
+	loc, err := acct.Location()
+	if err != nil {
+		return nil, err
 	}
+	return &TiktokSource{
+		Tokens:       tokenProviderFor(acct, tiktokTokenURL, TokenFormatTiktok),
+		AdvertiserID: acct.AccountID,
+		BaseURL:      tiktokDefaultBaseURL,
+		Client:       NewAPIClient("tiktok"),
+		Paging:       PagingFromEnv(),
+		Location:     loc,
+		Currency:     acct.CurrencyCode(),
+	}, nil
+}
 
-	body, _ := json.Marshal(payload)
+// Name returns the source name used in ENABLED_SOURCES
+func (s *TiktokSource) Name() string { return "tiktok" }
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	req.Header.Set("Access-Token", token)
-	req.Header.Set("Content-Type", "application/json")
+// Fetch pulls campaign-level report rows for the given window, one per day.
+// It walks page_info until total_page is reached or the MaxPages cap.
+func (s *TiktokSource) Fetch(ctx context.Context, window Window) ([]models.CampaignMetrics, error) {
+	fmt.Println("[TIKTOK] Fetching campaign data from TikTok Marketing API...")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("[TIKTOK] Request failed: %v\n", err)
-		return
+	if s.Tokens == nil || s.AdvertiserID == "" {
+		return nil, fmt.Errorf("tiktok: missing API credentials")
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[TIKTOK] API returned non-200: %d\n", resp.StatusCode)
-		return
+	paging := s.Paging.withDefaults()
+	if paging.PageSize > tiktokMaxPageSize {
+		paging.PageSize = tiktokMaxPageSize
 	}
 
-	respBody, _ := ioutil.ReadAll(resp.Body)
-	var response struct {
-		Data struct {
-			List []struct {
-				CampaignID  string  `json:"campaign_id"`
-				Impressions int     `json:"impressions"`
-				Clicks      int     `json:"clicks"`
-				Spend       float64 `json:"spend"`
-			} `json:"list"`
-		} `json:"data"`
-	}
+	endpoint := s.BaseURL + "/report/integrated/get/"
 
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		fmt.Printf("[TIKTOK] Failed to parse response: %v\n", err)
-		return
-	}
+	var metrics []models.CampaignMetrics
+	for page := 1; ; page++ {
+		if page > paging.MaxPages {
+			warnPageCap("TIKTOK", paging)
+			break
+		}
+
+		payload := map[string]interface{}{
+			"advertiser_id": s.AdvertiserID,
+			"report_type":   "BASIC",
+			"dimensions":    []string{"campaign_id", "stat_time_day"},
+			"metrics":       []string{"impressions", "clicks", "spend", "conversion", "total_purchase_value"},
+			"data_level":    "CAMPAIGN",
+			"start_date":    window.From.Format("2006-01-02"),
+			"end_date":      window.To.Format("2006-01-02"),
+			"page":          page,
+			"page_size":     paging.PageSize,
+		}
+		body, _ := json.Marshal(payload)
+
+		build := func(token string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Access-Token", token)
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		}
+
+		var response struct {
+			Data struct {
+				List []struct {
+					Dimensions struct {
+						CampaignID  string `json:"campaign_id"`
+						StatTimeDay string `json:"stat_time_day"`
+					} `json:"dimensions"`
+					Metrics struct {
+						Impressions        string `json:"impressions"`
+						Clicks             string `json:"clicks"`
+						Spend              string `json:"spend"`
+						Conversion         string `json:"conversion"`
+						TotalPurchaseValue string `json:"total_purchase_value"`
+					} `json:"metrics"`
+				} `json:"list"`
+				PageInfo struct {
+					Page      int `json:"page"`
+					TotalPage int `json:"total_page"`
+				} `json:"page_info"`
+			} `json:"data"`
+		}
+		if err := doAuthorizedJSON(ctx, s.Client, s.Tokens, build, &response); err != nil {
+			return nil, fmt.Errorf("tiktok: %w", err)
+		}
+
+		for _, item := range response.Data.List {
+			spend, err := models.ParseMicros(item.Metrics.Spend)
+			if err != nil {
+				return nil, fmt.Errorf("tiktok: campaign %s spend: %w", item.Dimensions.CampaignID, err)
+			}
+			revenue, err := models.ParseMicros(item.Metrics.TotalPurchaseValue)
+			if err != nil {
+				return nil, fmt.Errorf("tiktok: campaign %s total_purchase_value: %w", item.Dimensions.CampaignID, err)
+			}
+			date, timestamp, err := reportingDay(item.Dimensions.StatTimeDay, s.Location)
+			if err != nil {
+				return nil, fmt.Errorf("tiktok: %w", err)
+			}
+
+			metrics = append(metrics, models.CampaignMetrics{
+				CampaignID:  fmt.Sprintf("t-%s", item.Dimensions.CampaignID),
+				Platform:    "TikTok",
+				AccountID:   s.AdvertiserID,
+				Impressions: atoi(item.Metrics.Impressions),
+				Clicks:      atoi(item.Metrics.Clicks),
+				Conversions: atoi(item.Metrics.Conversion),
+				Cost:        spend,
+				Revenue:     revenue,
+				Currency:    s.Currency,
+				Timestamp:   timestamp,
+				Date:        date,
+			})
+		}
 
-	for _, item := range response.Data.List {
-		metric := models.CampaignMetrics{
-			CampaignID:  fmt.Sprintf("t-%s", item.CampaignID),
-			Platform:    "TikTok",
-			Impressions: item.Impressions,
-			Clicks:      item.Clicks,
-			Conversions: 0,
-			Cost:        item.Spend,
-			Revenue:     0.0,
-			Timestamp:   time.Now().UTC().String(),
+		if len(response.Data.List) == 0 || page >= response.Data.PageInfo.TotalPage {
+			break
 		}
-		processor.ProcessMetric(metric)
 	}
+	return metrics, nil
 }
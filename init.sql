@@ -2,15 +2,90 @@ CREATE TABLE IF NOT EXISTS campaign_metrics (
     id SERIAL PRIMARY KEY,
     campaign_id TEXT NOT NULL,
     platform TEXT NOT NULL,
+    account_id TEXT,
     impressions INT DEFAULT 0,
     clicks INT DEFAULT 0,
     conversions INT DEFAULT 0,
-    cost NUMERIC(10, 2) DEFAULT 0.00,
-    revenue NUMERIC(10, 2) DEFAULT 0.00,
-    timestamp TIMESTAMP NOT NULL,
+    cost NUMERIC(20, 6) DEFAULT 0,
+    revenue NUMERIC(20, 6) DEFAULT 0,
+    timestamp TIMESTAMPTZ NOT NULL,
+    date DATE,
+    currency TEXT,
+    original_cost NUMERIC(20, 6),
+    original_revenue NUMERIC(20, 6),
+    fx_rate NUMERIC(18, 8),
+    revision INT NOT NULL DEFAULT 0,
+    restated_at TIMESTAMPTZ,
     UNIQUE (campaign_id, timestamp)
 );
 
+-- Columns added after the initial schema; safe to re-run on existing databases
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 0;
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS restated_at TIMESTAMP;
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS account_id TEXT;
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS date DATE;
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS currency TEXT;
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS original_cost NUMERIC(20, 6);
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS original_revenue NUMERIC(20, 6);
+ALTER TABLE campaign_metrics ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(18, 8);
+
+-- Older databases stored timestamps without a zone; their values were UTC
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'campaign_metrics' AND column_name = 'timestamp'
+                 AND data_type = 'timestamp without time zone') THEN
+        ALTER TABLE campaign_metrics
+            ALTER COLUMN timestamp TYPE TIMESTAMPTZ USING timestamp AT TIME ZONE 'UTC',
+            ALTER COLUMN restated_at TYPE TIMESTAMPTZ USING restated_at AT TIME ZONE 'UTC';
+    END IF;
+END $$;
+
+-- Money was stored as NUMERIC(10, 2), which overflows at 100 million and drops
+-- sub-cent amounts; widen it to match the micros precision of the app
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'campaign_metrics' AND column_name = 'cost' AND numeric_scale = 2) THEN
+        ALTER TABLE campaign_metrics
+            ALTER COLUMN cost TYPE NUMERIC(20, 6),
+            ALTER COLUMN revenue TYPE NUMERIC(20, 6),
+            ALTER COLUMN original_cost TYPE NUMERIC(20, 6),
+            ALTER COLUMN original_revenue TYPE NUMERIC(20, 6);
+    END IF;
+END $$;
+
+-- Rows written before the date column existed report in UTC days
+UPDATE campaign_metrics SET date = (timestamp AT TIME ZONE 'UTC')::date WHERE date IS NULL;
+
+CREATE INDEX IF NOT EXISTS campaign_metrics_account_id_idx ON campaign_metrics (account_id, timestamp);
+CREATE INDEX IF NOT EXISTS campaign_metrics_date_idx ON campaign_metrics (campaign_id, date);
+-- Date-range reports across campaigns (see reportingDayRange in storage/series.go)
+CREATE INDEX IF NOT EXISTS campaign_metrics_day_idx ON campaign_metrics (date);
+CREATE INDEX IF NOT EXISTS campaign_metrics_undated_idx ON campaign_metrics (timestamp) WHERE date IS NULL;
+
+-- One row per fetch of one ad account; error is NULL for successful runs
+CREATE TABLE IF NOT EXISTS ingestion_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL,
+    account_id TEXT NOT NULL DEFAULT '',
+    window_from TIMESTAMPTZ NOT NULL,
+    window_to TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    rows_fetched INT NOT NULL DEFAULT 0,
+    rows_inserted INT NOT NULL DEFAULT 0,
+    rows_updated INT NOT NULL DEFAULT 0,
+    rows_deduped INT NOT NULL DEFAULT 0,
+    rows_failed INT NOT NULL DEFAULT 0,
+    error TEXT,
+    error_kind TEXT,
+    error_code TEXT
+);
+
+CREATE INDEX IF NOT EXISTS ingestion_runs_source_idx ON ingestion_runs (source, account_id, finished_at DESC);
+CREATE INDEX IF NOT EXISTS ingestion_runs_started_at_idx ON ingestion_runs (started_at DESC);
+
 CREATE EXTENSION IF NOT EXISTS vector;
 
 CREATE TABLE IF NOT EXISTS campaign_embeddings (
@@ -0,0 +1,201 @@
+// metrics/expr.go
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed arithmetic expression over the fields of Totals
+type Expr interface {
+	// Eval returns the value of the expression for t. ok is false when the
+	// value is undefined, e.g. after a division by zero.
+	Eval(t Totals) (v float64, ok bool)
+}
+
+// fields are the identifiers an expression may use. Money is in currency units.
+var fields = map[string]func(Totals) float64{
+	"impressions": func(t Totals) float64 { return float64(t.Impressions) },
+	"clicks":      func(t Totals) float64 { return float64(t.Clicks) },
+	"conversions": func(t Totals) float64 { return float64(t.Conversions) },
+	"cost":        func(t Totals) float64 { return t.Cost.Float64() },
+	"revenue":     func(t Totals) float64 { return t.Revenue.Float64() },
+}
+
+type number float64
+
+func (n number) Eval(Totals) (float64, bool) { return float64(n), true }
+
+type field string
+
+func (f field) Eval(t Totals) (float64, bool) { return fields[string(f)](t), true }
+
+type negate struct{ x Expr }
+
+func (n negate) Eval(t Totals) (float64, bool) {
+	v, ok := n.x.Eval(t)
+	return -v, ok
+}
+
+type binary struct {
+	op   byte
+	l, r Expr
+}
+
+func (b binary) Eval(t Totals) (float64, bool) {
+	l, ok := b.l.Eval(t)
+	if !ok {
+		return 0, false
+	}
+	r, ok := b.r.Eval(t)
+	if !ok {
+		return 0, false
+	}
+	var v float64
+	switch b.op {
+	case '+':
+		v = l + r
+	case '-':
+		v = l - r
+	case '*':
+		v = l * r
+	case '/':
+		if r == 0 {
+			return 0, false
+		}
+		v = l / r
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// ParseExpr parses an expression such as "(revenue - cost) / cost". It
+// supports numbers, the fields impressions, clicks, conversions, cost and
+// revenue, parentheses, unary minus and + - * / with the usual precedence.
+func ParseExpr(s string) (Expr, error) {
+	p := &parser{src: s}
+	p.next()
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, p.errorf("unexpected %q", p.tok)
+	}
+	return e, nil
+}
+
+// parser is a recursive-descent parser over a one-token lookahead
+type parser struct {
+	src string
+	pos int    // offset just past tok
+	tok string // current token, "" at the end
+	at  int    // offset of tok
+}
+
+// next advances to the following token
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.at = p.pos
+	if p.pos == len(p.src) {
+		p.tok = ""
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[p.at:p.pos]
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %d: %s", p.at+1, fmt.Sprintf(format, args...))
+}
+
+// expr := term (("+" | "-") term)*
+func (p *parser) expr() (Expr, error) {
+	l, err := p.term()
+	for err == nil && (p.tok == "+" || p.tok == "-") {
+		op := p.tok[0]
+		p.next()
+		var r Expr
+		if r, err = p.term(); err == nil {
+			l = binary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+// term := unary (("*" | "/") unary)*
+func (p *parser) term() (Expr, error) {
+	l, err := p.unary()
+	for err == nil && (p.tok == "*" || p.tok == "/") {
+		op := p.tok[0]
+		p.next()
+		var r Expr
+		if r, err = p.unary(); err == nil {
+			l = binary{op, l, r}
+		}
+	}
+	return l, err
+}
+
+// unary := "-" unary | primary
+func (p *parser) unary() (Expr, error) {
+	if p.tok == "-" {
+		p.next()
+		x, err := p.unary()
+		return negate{x}, err
+	}
+	return p.primary()
+}
+
+// primary := number | field | "(" expr ")"
+func (p *parser) primary() (Expr, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, p.errorf("unexpected end of expression")
+	case tok == "(":
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, p.errorf("expected )")
+		}
+		p.next()
+		return e, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok)
+		}
+		p.next()
+		return number(v), nil
+	case fields[strings.ToLower(tok)] != nil:
+		p.next()
+		return field(strings.ToLower(tok)), nil
+	case tok[0] == '_' || unicode.IsLetter(rune(tok[0])):
+		return nil, p.errorf("unknown field %q", tok)
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
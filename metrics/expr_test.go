@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"campaign-analytics/models"
+)
+
+func TestParseExprEval(t *testing.T) {
+	totals := Totals{Impressions: 2000, Clicks: 40, Conversions: 4, Cost: 80 * models.MicrosPerUnit, Revenue: 200 * models.MicrosPerUnit}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"cost / clicks", 2},
+		{"(revenue - cost) / cost", 1.5},
+		{"revenue * 0.3 / cost", 0.75},
+		{"cost / impressions * 1000", 40},
+		{"1 + 2 * 3", 7},
+		{"-cost + -(-revenue)", 120},
+		{"Clicks/Impressions", 0.02},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		if got, ok := e.Eval(totals); !ok || got != tt.want {
+			t.Errorf("%s = %v (ok %v), want %v", tt.expr, got, ok, tt.want)
+		}
+	}
+
+	// Division by zero anywhere makes the whole value undefined
+	e, _ := ParseExpr("(revenue - cost) / cost + 1")
+	if v, ok := e.Eval(Totals{Revenue: 5}); ok {
+		t.Errorf("got %v, want undefined", v)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, expr := range []string{"", "cost /", "(cost", "cost clicks", "spend / clicks", "cost % clicks", "1..2", "os.Exit(1)"} {
+		if _, err := ParseExpr(expr); err == nil {
+			t.Errorf("ParseExpr(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestRegistryLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	os.WriteFile(path, []byte(`{"metrics": [
+		{"name": "blended_roas_30", "expression": "revenue * 0.3 / cost"},
+		{"name": "Cost_Per_Conv", "expression": "cost / conversions"}
+	]}`), 0o644)
+
+	r := NewRegistry()
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	// Built-in figures can be selected next to custom metrics
+	names, err := r.Resolve([]string{"blended_roas_30", " cost_per_conv", "ROAS", "cost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := r.Evaluate(Totals{Cost: 10 * models.MicrosPerUnit, Revenue: 50 * models.MicrosPerUnit}, names)
+	if v := values["blended_roas_30"]; v == nil || *v != 1.5 {
+		t.Errorf("blended_roas_30 = %v, want 1.5", v)
+	}
+	if roas, cost := values["roas"], values["cost"]; roas == nil || *roas != 5 || cost == nil || *cost != 10 {
+		t.Errorf("roas, cost = %v, %v; want 5, 10", roas, cost)
+	}
+	if v, ok := values["cost_per_conv"]; !ok || v != nil {
+		t.Errorf("cost_per_conv = %v, want undefined", v)
+	}
+	if _, err := r.Resolve([]string{"nope"}); err == nil {
+		t.Error("Resolve(nope) succeeded, want error")
+	}
+
+	// A bad definition rejects the whole file
+	os.WriteFile(path, []byte(`{"metrics": [{"name": "ok", "expression": "clicks"}, {"name": "roas", "expression": "revenue / cost"}]}`), 0o644)
+	r = NewRegistry()
+	if err := r.Load(path); err == nil || len(r.Definitions()) != 0 {
+		t.Errorf("Load with a built-in name: err %v, %d definitions registered", err, len(r.Definitions()))
+	}
+}
@@ -0,0 +1,157 @@
+// metrics/metrics.go
+package metrics
+
+import (
+	"math"
+
+	"campaign-analytics/models"
+)
+
+// Totals are the additive components every derived metric is computed from.
+// Sum rows into Totals and compute ratios from the sums; averaging per-row
+// ratios would weight a row with ten impressions like one with a million.
+type Totals struct {
+	Impressions int64         `json:"impressions"`
+	Clicks      int64         `json:"clicks"`
+	Conversions int64         `json:"conversions"`
+	Cost        models.Micros `json:"cost"`
+	Revenue     models.Micros `json:"revenue"`
+}
+
+// Of returns the totals of a single row
+func Of(m models.CampaignMetrics) Totals {
+	return Totals{
+		Impressions: int64(m.Impressions),
+		Clicks:      int64(m.Clicks),
+		Conversions: int64(m.Conversions),
+		Cost:        m.Cost,
+		Revenue:     m.Revenue,
+	}
+}
+
+// Add sums o into t
+func (t *Totals) Add(o Totals) {
+	t.Impressions += o.Impressions
+	t.Clicks += o.Clicks
+	t.Conversions += o.Conversions
+	t.Cost += o.Cost
+	t.Revenue += o.Revenue
+}
+
+// Derived are the metrics computed from Totals. A metric whose denominator is
+// zero is nil (null in JSON) rather than 0 or infinity. Money-valued metrics
+// are in the same currency as the totals.
+type Derived struct {
+	CTR    *float64       `json:"ctr"`  // clicks / impressions
+	CPC    *models.Micros `json:"cpc"`  // cost / clicks
+	CPM    *models.Micros `json:"cpm"`  // cost per 1000 impressions
+	CVR    *float64       `json:"cvr"`  // conversions / clicks
+	CPA    *models.Micros `json:"cpa"`  // cost / conversions
+	ROAS   *float64       `json:"roas"` // revenue / cost
+	AOV    *models.Micros `json:"aov"`  // revenue / conversions
+	Profit models.Micros  `json:"profit"`
+}
+
+// Compute derives every metric from t
+func Compute(t Totals) Derived {
+	return Derived{
+		CTR:    ratio(float64(t.Clicks), float64(t.Impressions)),
+		CPC:    perUnit(t.Cost, 1, t.Clicks),
+		CPM:    perUnit(t.Cost, 1000, t.Impressions),
+		CVR:    ratio(float64(t.Conversions), float64(t.Clicks)),
+		CPA:    perUnit(t.Cost, 1, t.Conversions),
+		ROAS:   ratio(float64(t.Revenue), float64(t.Cost)),
+		AOV:    perUnit(t.Revenue, 1, t.Conversions),
+		Profit: t.Revenue - t.Cost,
+	}
+}
+
+// Report is a set of totals together with the metrics derived from them
+type Report struct {
+	Totals
+	Derived
+}
+
+// NewReport computes the report for t
+func NewReport(t Totals) Report {
+	return Report{Totals: t, Derived: Compute(t)}
+}
+
+func ratio(num, den float64) *float64 {
+	if den == 0 {
+		return nil
+	}
+	r := num / den
+	return &r
+}
+
+// perUnit is amount*scale/n rounded to the nearest micro
+func perUnit(amount models.Micros, scale float64, n int64) *models.Micros {
+	if n == 0 {
+		return nil
+	}
+	v := models.Micros(math.Round(float64(amount) * scale / float64(n)))
+	return &v
+}
+
+// Values returns every figure of the report by its JSON name, with money in
+// currency units and nil for undefined ratios
+func (r Report) Values() Values {
+	return Values{
+		"impressions": number64(float64(r.Impressions)),
+		"clicks":      number64(float64(r.Clicks)),
+		"conversions": number64(float64(r.Conversions)),
+		"cost":        number64(r.Cost.Float64()),
+		"revenue":     number64(r.Revenue.Float64()),
+		"ctr":         r.CTR,
+		"cpc":         money(r.CPC),
+		"cpm":         money(r.CPM),
+		"cvr":         r.CVR,
+		"cpa":         money(r.CPA),
+		"roas":        r.ROAS,
+		"aov":         money(r.AOV),
+		"profit":      number64(r.Profit.Float64()),
+	}
+}
+
+// Change compares one figure across two periods. Delta is current minus
+// previous, and Percent is Delta relative to previous (0.25 for +25%). Either
+// is nil when it's undefined, e.g. Percent when previous was 0.
+type Change struct {
+	Delta   *float64 `json:"delta"`
+	Percent *float64 `json:"percent"`
+}
+
+// moneyFigures are the Values names holding amounts of money
+var moneyFigures = map[string]bool{
+	"cost": true, "revenue": true, "cpc": true, "cpm": true, "cpa": true, "aov": true, "profit": true,
+}
+
+// Compare returns the change of every figure in current versus previous
+func Compare(current, previous Values) map[string]Change {
+	changes := make(map[string]Change, len(current))
+	for name, cur := range current {
+		prev := previous[name]
+		var c Change
+		if cur != nil && prev != nil {
+			delta := *cur - *prev
+			if moneyFigures[name] {
+				// Undo float drift so 12.34 - 10.01 reads 2.33
+				delta = math.Round(delta*models.MicrosPerUnit) / models.MicrosPerUnit
+			}
+			c.Delta = number64(delta)
+			c.Percent = ratio(delta, math.Abs(*prev))
+		}
+		changes[name] = c
+	}
+	return changes
+}
+
+func number64(v float64) *float64 { return &v }
+
+func money(m *models.Micros) *float64 {
+	if m == nil {
+		return nil
+	}
+	return number64(m.Float64())
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"campaign-analytics/models"
+)
+
+func TestComputeFromSums(t *testing.T) {
+	// A small row with a high CTR and a large one with a low CTR: the
+	// combined CTR must follow the large row, not the average of the two
+	rows := []models.CampaignMetrics{
+		{Impressions: 100, Clicks: 50, Conversions: 5, Cost: 10 * models.MicrosPerUnit, Revenue: 50 * models.MicrosPerUnit},
+		{Impressions: 9900, Clicks: 50, Conversions: 5, Cost: 90 * models.MicrosPerUnit, Revenue: 150 * models.MicrosPerUnit},
+	}
+	var total Totals
+	for _, m := range rows {
+		total.Add(Of(m))
+	}
+	d := Compute(total)
+
+	if *d.CTR != 0.01 {
+		t.Errorf("CTR = %v, want 0.01", *d.CTR)
+	}
+	if *d.ROAS != 2 {
+		t.Errorf("ROAS = %v, want 2", *d.ROAS)
+	}
+	if d.CPC.String() != "1" || d.CPM.String() != "10" || d.CPA.String() != "10" || d.AOV.String() != "20" {
+		t.Errorf("CPC/CPM/CPA/AOV = %v/%v/%v/%v, want 1/10/10/20", d.CPC, d.CPM, d.CPA, d.AOV)
+	}
+	if *d.CVR != 0.1 || d.Profit != 100*models.MicrosPerUnit {
+		t.Errorf("CVR = %v, profit = %v; want 0.1, 100", *d.CVR, d.Profit)
+	}
+}
+
+func TestComputeZeroDenominators(t *testing.T) {
+	d := Compute(Totals{Revenue: 5 * models.MicrosPerUnit})
+	if d.CTR != nil || d.CPC != nil || d.CPM != nil || d.CVR != nil || d.CPA != nil || d.ROAS != nil || d.AOV != nil {
+		t.Errorf("got %+v, want every ratio nil", d)
+	}
+
+	data, _ := json.Marshal(NewReport(Totals{}))
+	want := `{"impressions":0,"clicks":0,"conversions":0,"cost":0,"revenue":0,"ctr":null,"cpc":null,"cpm":null,"cvr":null,"cpa":null,"roas":null,"aov":null,"profit":0}`
+	if string(data) != want {
+		t.Errorf("JSON = %s\nwant   %s", data, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cur := NewReport(Totals{Impressions: 1500, Clicks: 30, Cost: 60 * models.MicrosPerUnit, Revenue: 90 * models.MicrosPerUnit})
+	prev := NewReport(Totals{Impressions: 1000, Clicks: 10})
+	changes := Compare(cur.Values(), prev.Values())
+
+	if c := changes["impressions"]; *c.Delta != 500 || *c.Percent != 0.5 {
+		t.Errorf("impressions change = %v/%v, want 500/0.5", *c.Delta, *c.Percent)
+	}
+	if c := changes["ctr"]; *c.Delta != 0.01 || *c.Percent != 1 {
+		t.Errorf("ctr change = %v/%v, want 0.01/1", *c.Delta, *c.Percent)
+	}
+	// Spend went from 0 to 60: the delta is known, the percentage isn't
+	if c := changes["cost"]; *c.Delta != 60 || c.Percent != nil {
+		t.Errorf("cost change = %v/%v, want 60/nil", *c.Delta, c.Percent)
+	}
+	if c := Compare(Values{"cost": number64(12.34)}, Values{"cost": number64(10.01)})["cost"]; *c.Delta != 2.33 {
+		t.Errorf("cost delta = %v, want 2.33", *c.Delta)
+	}
+	// ROAS was undefined without spend
+	if c := changes["roas"]; c.Delta != nil || c.Percent != nil {
+		t.Errorf("roas change = %+v, want undefined", c)
+	}
+}
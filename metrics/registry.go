@@ -0,0 +1,172 @@
+// metrics/registry.go
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Definition is a named custom metric
+type Definition struct {
+	Name        string `json:"name"`
+	Expression  string `json:"expression"`
+	Description string `json:"description,omitempty"`
+
+	expr Expr
+}
+
+// Values maps metric names to their values; nil means undefined
+type Values map[string]*float64
+
+// Registry holds custom metric definitions by name
+type Registry struct {
+	mu   sync.RWMutex
+	defs map[string]Definition
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{defs: make(map[string]Definition)}
+}
+
+// Custom is the registry the API serves metrics= from
+var Custom = NewRegistry()
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinNames are the metrics Compute returns; custom metrics can't shadow them
+var builtinNames = map[string]bool{
+	"ctr": true, "cpc": true, "cpm": true, "cvr": true,
+	"cpa": true, "roas": true, "aov": true, "profit": true,
+}
+
+// Register parses d.Expression and adds d, replacing any definition with the same name
+func (r *Registry) Register(d Definition) error {
+	d.Name = strings.ToLower(strings.TrimSpace(d.Name))
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid metric name %q: use lowercase letters, digits and _", d.Name)
+	}
+	if builtin(d.Name) {
+		return fmt.Errorf("metric name %q is already a built-in metric or field", d.Name)
+	}
+	expr, err := ParseExpr(d.Expression)
+	if err != nil {
+		return fmt.Errorf("metric %s: %w", d.Name, err)
+	}
+	d.expr = expr
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defs[d.Name] = d
+	return nil
+}
+
+// Definitions returns every definition sorted by name
+func (r *Registry) Definitions() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]Definition, 0, len(r.defs))
+	for _, d := range r.defs {
+		defs = append(defs, d)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// builtin reports whether name is a Totals field or a Compute metric; these
+// can be requested by name alongside custom metrics
+func builtin(name string) bool {
+	return builtinNames[name] || fields[name] != nil
+}
+
+// Resolve checks that every name is registered or built in, returning them
+// normalized
+func (r *Registry) Resolve(names []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := r.defs[name]; !ok && !builtin(name) {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		resolved = append(resolved, name)
+	}
+	return resolved, nil
+}
+
+// Evaluate computes the named metrics for t, built-in ones as in Report.Values.
+// Unknown names are skipped; check them with Resolve first.
+func (r *Registry) Evaluate(t Totals, names []string) Values {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	values := make(Values, len(names))
+	var report Values
+	for _, name := range names {
+		if builtin(name) {
+			if report == nil {
+				report = NewReport(t).Values()
+			}
+			values[name] = report[name]
+			continue
+		}
+		d, ok := r.defs[name]
+		if !ok {
+			continue
+		}
+		if v, ok := d.expr.Eval(t); ok {
+			values[name] = &v
+		} else {
+			values[name] = nil
+		}
+	}
+	return values
+}
+
+// MetricsConfig is the on-disk format of METRICS_FILE
+type MetricsConfig struct {
+	Metrics []Definition `json:"metrics"`
+}
+
+// Load registers every definition in the JSON file at path. Nothing is
+// registered if any definition is invalid.
+func (r *Registry) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read metrics file: %w", err)
+	}
+	var cfg MetricsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parse metrics file: %w", err)
+	}
+
+	staged := NewRegistry()
+	for i, d := range cfg.Metrics {
+		if err := staged.Register(d); err != nil {
+			return fmt.Errorf("metrics[%d]: %w", i, err)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, d := range staged.defs {
+		r.defs[name] = d
+	}
+	return nil
+}
+
+// LoadDefault loads Custom from METRICS_FILE, if set
+func LoadDefault() error {
+	path := os.Getenv("METRICS_FILE")
+	if path == "" {
+		return nil
+	}
+	return Custom.Load(path)
+}
@@ -1,13 +1,35 @@
 package models
 
+import "time"
+
 // CampaignMetrics defines the structure for ad campaign analytics data.
 type CampaignMetrics struct {
-	CampaignID  string  `json:"campaign_id" db:"campaign_id"`
-	Platform    string  `json:"platform" db:"platform"`
-	Impressions int     `json:"impressions" db:"impressions"`
-	Clicks      int     `json:"clicks" db:"clicks"`
-	Conversions int     `json:"conversions" db:"conversions"`
-	Cost        float64 `json:"cost" db:"cost"`
-	Revenue     float64 `json:"revenue" db:"revenue"`
-	Timestamp   string  `json:"timestamp" db:"timestamp"`
+	CampaignID  string `json:"campaign_id" db:"campaign_id"`
+	Platform    string `json:"platform" db:"platform"`
+	AccountID   string `json:"account_id,omitempty" db:"account_id"`
+	Impressions int    `json:"impressions" db:"impressions"`
+	Clicks      int    `json:"clicks" db:"clicks"`
+	Conversions int    `json:"conversions" db:"conversions"`
+	Cost        Micros `json:"cost" db:"cost"`
+	Revenue     Micros `json:"revenue" db:"revenue"`
+
+	// Cost and Revenue are in the reporting currency. Currency is what the
+	// platform reported in; the platform's own amounts are kept in
+	// OriginalCost and OriginalRevenue, converted at FXRate.
+	Currency        string  `json:"currency,omitempty" db:"currency"`
+	OriginalCost    Micros  `json:"original_cost" db:"original_cost"`
+	OriginalRevenue Micros  `json:"original_revenue" db:"original_revenue"`
+	FXRate          float64 `json:"fx_rate,omitempty" db:"fx_rate"`
+
+	// Timestamp is when the row's reporting period starts. It is an RFC3339
+	// string in JSON and a TIMESTAMPTZ in the database.
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	// Date is the reporting day (YYYY-MM-DD) in the ad account's timezone, so
+	// daily reports group rows the way the platform does
+	Date string `json:"date,omitempty" db:"date"`
+
+	// Revision counts how many times the platform restated this row; RestatedAt
+	// is when that last happened (nil while the first reported value stands)
+	Revision   int        `json:"revision" db:"revision"`
+	RestatedAt *time.Time `json:"restated_at,omitempty" db:"restated_at"`
 }
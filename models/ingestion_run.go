@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// IngestionRun records one fetch of one ad account and what it stored
+type IngestionRun struct {
+	ID           int64     `json:"id" db:"id"`
+	Source       string    `json:"source" db:"source"`
+	AccountID    string    `json:"account_id" db:"account_id"`
+	WindowFrom   time.Time `json:"window_from" db:"window_from"`
+	WindowTo     time.Time `json:"window_to" db:"window_to"`
+	StartedAt    time.Time `json:"started_at" db:"started_at"`
+	FinishedAt   time.Time `json:"finished_at" db:"finished_at"`
+	RowsFetched  int       `json:"rows_fetched" db:"rows_fetched"`
+	RowsInserted int       `json:"rows_inserted" db:"rows_inserted"`
+	RowsUpdated  int       `json:"rows_updated" db:"rows_updated"`
+	RowsDeduped  int       `json:"rows_deduped" db:"rows_deduped"`
+	RowsFailed   int       `json:"rows_failed" db:"rows_failed"`
+	Error        string    `json:"error,omitempty" db:"error"`
+	ErrorKind    string    `json:"error_kind,omitempty" db:"error_kind"`
+	ErrorCode    string    `json:"error_code,omitempty" db:"error_code"`
+}
+
+// Succeeded is true when the fetch itself did not fail
+func (r IngestionRun) Succeeded() bool {
+	return r.Error == ""
+}
+
+// SourceSync is the latest successful run of one source and account
+type SourceSync struct {
+	Source         string     `json:"source"`
+	AccountID      string     `json:"account_id"`
+	LastSuccessAt  time.Time  `json:"last_success_at"`
+	LastWindowTo   time.Time  `json:"last_window_to"`
+	LastRowsStored int        `json:"last_rows_stored"`
+	LastFailureAt  *time.Time `json:"last_failure_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MicrosPerUnit is how many Micros make one currency unit
+const MicrosPerUnit = 1_000_000
+
+// Micros is an amount of money in millionths of a currency unit. Integer
+// micros add up exactly, unlike float64, and match Google's cost_micros. It
+// is a decimal number in JSON and NUMERIC in the database.
+type Micros int64
+
+// MicrosFromFloat converts f currency units to Micros, rounding to the nearest micro
+func MicrosFromFloat(f float64) Micros {
+	return Micros(math.Round(f * MicrosPerUnit))
+}
+
+// ParseMicros parses a decimal amount such as "1234.56" exactly. Digits
+// past the sixth decimal place are rounded half away from zero.
+func ParseMicros(s string) (Micros, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		return MicrosFromFloat(f), nil
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimLeft(s, "+-")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	var m int64
+	for i, r := range whole + padFraction(frac) {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		if i >= len(whole)+6 {
+			// Round on the first dropped digit
+			if r >= '5' {
+				m++
+			}
+			break
+		}
+		if m > (math.MaxInt64-9)/10 {
+			return 0, fmt.Errorf("amount %q out of range", s)
+		}
+		m = m*10 + int64(r-'0')
+	}
+	if neg {
+		m = -m
+	}
+	return Micros(m), nil
+}
+
+// padFraction right-pads frac to at least six digits
+func padFraction(frac string) string {
+	if len(frac) < 6 {
+		return frac + strings.Repeat("0", 6-len(frac))
+	}
+	return frac
+}
+
+// Float64 returns m in currency units, for ratios and display only
+func (m Micros) Float64() float64 {
+	return float64(m) / MicrosPerUnit
+}
+
+// Convert multiplies m by an exchange rate, rounding to the nearest micro
+func (m Micros) Convert(rate float64) Micros {
+	return Micros(math.Round(float64(m) * rate))
+}
+
+// String formats m as a decimal without trailing zeros, e.g. "12.5"
+func (m Micros) String() string {
+	sign := ""
+	u := uint64(m)
+	if m < 0 {
+		sign, u = "-", uint64(-m)
+	}
+	s := fmt.Sprintf("%s%d.%06d", sign, u/MicrosPerUnit, u%MicrosPerUnit)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON writes m as an exact JSON number
+func (m Micros) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number, or a number in a string as some platforms send
+func (m *Micros) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if string(data) == "null" {
+		*m = 0
+		return nil
+	}
+	v, err := ParseMicros(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value implements driver.Valuer, sending m as a NUMERIC literal
+func (m Micros) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns; NULL scans as 0
+func (m *Micros) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.UnmarshalJSON(v)
+	case string:
+		return m.UnmarshalJSON([]byte(v))
+	case int64:
+		*m = Micros(v * MicrosPerUnit)
+	case float64:
+		*m = MicrosFromFloat(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Micros", src)
+	}
+	return nil
+}
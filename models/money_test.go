@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMicros(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Micros
+	}{
+		{"0", 0},
+		{"12.34", 12_340_000},
+		{"0.000001", 1},
+		{"-7.5", -7_500_000},
+		{"123456789.123456", 123_456_789_123_456},
+		{"1.2345675", 1_234_568}, // rounds half away from zero
+		{"-1.2345675", -1_234_568},
+		{"1e-6", 1},
+		{".5", 500_000},
+	}
+	for _, tt := range tests {
+		got, err := ParseMicros(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMicros(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"abc", "1.2.3", "-", "12,50"} {
+		if _, err := ParseMicros(bad); err == nil {
+			t.Errorf("ParseMicros(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestMicrosJSONRoundTrip(t *testing.T) {
+	for _, m := range []Micros{0, 1, 12_340_000, -7_500_000, 100_000_000_000_000} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Micros
+		if err := json.Unmarshal(data, &back); err != nil || back != m {
+			t.Errorf("%d marshalled as %s and read back as %d (%v)", m, data, back, err)
+		}
+	}
+
+	// Platforms send amounts as strings too
+	var m Micros
+	if err := json.Unmarshal([]byte(`"0.10"`), &m); err != nil || m != 100_000 {
+		t.Errorf(`Unmarshal("0.10") = %d, %v`, m, err)
+	}
+
+	// Ten cents added ten times is exactly one unit
+	var sum Micros
+	for i := 0; i < 10; i++ {
+		sum += m
+	}
+	if sum.String() != "1" {
+		t.Errorf("sum = %s, want 1", sum)
+	}
+}
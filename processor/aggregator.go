@@ -2,45 +2,131 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"campaign-analytics/metrics"
 	"campaign-analytics/models"
 	"campaign-analytics/storage"
 )
 
-// ProcessMetric calculates derived metrics and stores them in DB
-func ProcessMetric(m models.CampaignMetrics) {
-	// Calculate derived metrics
-	ctr := 0.0
-	if m.Impressions > 0 {
-		ctr = float64(m.Clicks) / float64(m.Impressions)
+// maxInsertAttempts is how often a write is tried when the database reports a transient error
+const maxInsertAttempts = 3
+
+// ProcessMetric logs the row's derived metrics and stores it in DB, returning
+// what the write did to the stored row
+func ProcessMetric(ctx context.Context, m models.CampaignMetrics) (storage.WriteResult, error) {
+	logDerived(m)
+	return storeMetric(ctx, m)
+}
+
+// storeMetric writes one row, dead-lettering it if every attempt fails
+func storeMetric(ctx context.Context, m models.CampaignMetrics) (storage.WriteResult, error) {
+	// Retry the insert only while the database reports transient errors
+	var result storage.WriteResult
+	attempts, err := retryInsert(ctx, func() error {
+		var err error
+		result, err = storage.InsertCampaignMetrics(ctx, m)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Final failure inserting into DB for %s: %v\n", m.CampaignID, err)
+		deadLetter([]models.CampaignMetrics{m}, err, attempts)
+		return result, err
 	}
+	countWrites(result)
+	return result, nil
+}
 
-	roas := 0.0
-	if m.Cost > 0 {
-		roas = m.Revenue / m.Cost
+// ProcessBatch logs the derived metrics of every row and stores them with one
+// batched write. results[i] is the outcome for batch[i]. errs is nil when every
+// row was stored, and otherwise holds the error of each row (nil for stored ones).
+func ProcessBatch(ctx context.Context, batch []models.CampaignMetrics) (results []storage.WriteResult, errs []error) {
+	for _, m := range batch {
+		logDerived(m)
 	}
 
-	cpa := 0.0
-	if m.Conversions > 0 {
-		cpa = m.Cost / float64(m.Conversions)
+	// The batch insert is idempotent, so a retry after a partial write is safe
+	attempts, err := retryInsert(ctx, func() error {
+		var err error
+		results, err = storage.InsertCampaignMetricsBatch(ctx, batch)
+		return err
+	})
+	if err == nil {
+		counts := countWrites(results...)
+		fmt.Printf("[PROCESSOR] Stored batch of %d: %s\n", len(batch), counts)
+		return results, nil
 	}
 
-	fmt.Printf("Processed Campaign: %s | CTR: %.2f | ROAS: %.2f | CPA: %.2f\n", m.CampaignID, ctr, roas, cpa)
+	// A bad row fails the whole statement; write rows one by one so only the
+	// offending ones are dead-lettered. Their metrics were logged above.
+	if errors.Is(err, storage.ErrConstraint) && len(batch) > 1 {
+		fmt.Printf("[PROCESSOR] Batch of %d rejected (%v), storing rows individually\n", len(batch), err)
+		results = make([]storage.WriteResult, len(batch))
+		errs = make([]error, len(batch))
+		failed := 0
+		for i, m := range batch {
+			results[i], errs[i] = storeMetric(ctx, m)
+			if errs[i] != nil {
+				failed++
+			}
+		}
+		if failed == 0 {
+			return results, nil
+		}
+		return results, errs
+	}
+
+	fmt.Printf("Final failure inserting batch of %d into DB: %v\n", len(batch), err)
+	deadLetter(batch, err, attempts)
+	errs = make([]error, len(batch))
+	for i := range errs {
+		errs[i] = err
+	}
+	return make([]storage.WriteResult, len(batch)), errs
+}
 
-	// Retry insert up to 3 times on error (excluding dedup conflict)
+// logDerived prints the metrics derived from a row
+func logDerived(m models.CampaignMetrics) {
+	d := metrics.Compute(metrics.Of(m))
+	fmt.Printf("Processed Campaign: %s | CTR: %s | ROAS: %s | CPA: %s\n", m.CampaignID, formatRatio(d.CTR), formatRatio(d.ROAS), formatMoney(d.CPA))
+}
+
+// formatRatio prints a ratio with two decimals, or n/a when it's undefined
+func formatRatio(r *float64) string {
+	if r == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f", *r)
+}
+
+// formatMoney prints an amount with two decimals, or n/a when it's undefined
+func formatMoney(m *models.Micros) string {
+	if m == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f", m.Float64())
+}
+
+// retryInsert runs insert until it succeeds, fails with a non-transient error,
+// or has been tried maxInsertAttempts times, waiting a second between attempts
+// unless ctx ends. It returns how many attempts were made.
+func retryInsert(ctx context.Context, insert func() error) (int, error) {
 	var err error
-	for i := 0; i < 3; i++ {
-		err = storage.InsertCampaignMetrics(m)
-		if err == nil {
+	attempts := 0
+	for attempts < maxInsertAttempts {
+		attempts++
+		err = insert()
+		if err == nil || !errors.Is(err, storage.ErrTransient) || ctx.Err() != nil || attempts == maxInsertAttempts {
 			break
 		}
-		fmt.Printf("Retrying DB insert (attempt %d) due to error: %v\n", i+1, err)
-		time.Sleep(1 * time.Second)
-	}
-
-	if err != nil {
-		fmt.Printf("Final failure inserting into DB for %s: %v\n", m.CampaignID, err)
+		fmt.Printf("Retrying DB insert (attempt %d) due to error: %v\n", attempts, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(1 * time.Second):
+		}
 	}
+	return attempts, err
 }
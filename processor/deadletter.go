@@ -0,0 +1,152 @@
+// processor/deadletter.go
+package processor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+// DeadLetter is a metric that could not be stored after all retries
+type DeadLetter struct {
+	ID       string                 `json:"id"`
+	FailedAt time.Time              `json:"failed_at"`
+	Attempts int                    `json:"attempts"`
+	Error    string                 `json:"error"`
+	Metric   models.CampaignMetrics `json:"metric"`
+}
+
+// DeadLetterPath is the append-only JSONL file failed metrics are written to,
+// read from DEAD_LETTER_FILE (default dead-letter.jsonl). A local file is used
+// so failures are kept even while the database is down.
+var DeadLetterPath = deadLetterPathFromEnv()
+
+func deadLetterPathFromEnv() string {
+	if path := os.Getenv("DEAD_LETTER_FILE"); path != "" {
+		return path
+	}
+	return "dead-letter.jsonl"
+}
+
+var (
+	deadLetterMu    sync.Mutex
+	deadLetterSeq   uint64
+	statDeadLetters = new(expvar.Int)
+)
+
+func init() {
+	pipelineVars.Set("dead_lettered", statDeadLetters)
+}
+
+// deadLetter records metrics that failed with err after attempts tries
+func deadLetter(metrics []models.CampaignMetrics, err error, attempts int) {
+	now := time.Now().UTC()
+	entries := make([]DeadLetter, len(metrics))
+	deadLetterMu.Lock()
+	for i, m := range metrics {
+		deadLetterSeq++
+		entries[i] = DeadLetter{
+			ID:       strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatUint(deadLetterSeq, 36),
+			FailedAt: now,
+			Attempts: attempts,
+			Error:    err.Error(),
+			Metric:   m,
+		}
+	}
+	deadLetterMu.Unlock()
+
+	if werr := AppendDeadLetters(DeadLetterPath, entries); werr != nil {
+		fmt.Printf("[DEADLETTER] Failed to write %d metric(s) to %s, they are lost: %v\n", len(entries), DeadLetterPath, werr)
+		return
+	}
+	statDeadLetters.Add(int64(len(entries)))
+	fmt.Printf("[DEADLETTER] Wrote %d metric(s) to %s\n", len(entries), DeadLetterPath)
+}
+
+// AppendDeadLetters appends entries to the dead-letter file at path
+func AppendDeadLetters(path string, entries []DeadLetter) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	deadLetterMu.Lock()
+	defer deadLetterMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	// Sync so a crash right after a failed insert doesn't lose the entry too
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadDeadLetters returns every entry in the dead-letter file at path. A
+// missing file means there are none.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ClaimDeadLetters atomically moves the dead-letter file aside and returns its
+// entries, so a running server keeps appending to a fresh file meanwhile. The
+// caller must pass whatever it doesn't resolve to ReleaseDeadLetters.
+func ClaimDeadLetters(path string) (entries []DeadLetter, claimed string, err error) {
+	claimed = fmt.Sprintf("%s.claimed-%d", path, time.Now().UnixNano())
+	if err := os.Rename(path, claimed); errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	entries, err = ReadDeadLetters(claimed)
+	if err != nil {
+		return nil, "", fmt.Errorf("entries left in %s: %w", claimed, err)
+	}
+	return entries, claimed, nil
+}
+
+// ReleaseDeadLetters appends keep back onto the live file and deletes the
+// claimed one
+func ReleaseDeadLetters(path, claimed string, keep []DeadLetter) error {
+	if claimed == "" {
+		return nil
+	}
+	if err := AppendDeadLetters(path, keep); err != nil {
+		return fmt.Errorf("claimed entries left in %s: %w", claimed, err)
+	}
+	return os.Remove(claimed)
+}
@@ -0,0 +1,494 @@
+// processor/pipeline.go
+package processor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"campaign-analytics/config"
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+// Policy is what Submit does when the queue is full
+type Policy string
+
+const (
+	// PolicyBlock makes Submit wait for room in the queue
+	PolicyBlock Policy = "block"
+	// PolicyDropOldest discards the oldest queued metric to make room
+	PolicyDropOldest Policy = "drop-oldest"
+	// PolicySpill appends overflow to a file that is fed back once the queue drains
+	PolicySpill Policy = "spill"
+)
+
+// ErrDropped is passed to an Item's Done when the drop-oldest policy evicted it
+var ErrDropped = errors.New("dropped from full processing queue")
+
+// ErrClosed is returned by Submit once Close has been called
+var ErrClosed = errors.New("processing pipeline is closed")
+
+// Item is one metric waiting to be processed. Done, if set, is called once with
+// the outcome of the write.
+type Item struct {
+	Metric models.CampaignMetrics
+	Done   func(storage.WriteResult, error)
+}
+
+// PipelineConfig sizes a Pipeline. Each worker writes up to BatchSize metrics
+// at once, flushing a partial batch after FlushInterval.
+type PipelineConfig struct {
+	Workers       int
+	QueueSize     int
+	Policy        Policy
+	SpillPath     string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// PipelineConfigFromEnv reads PIPELINE_WORKERS (default 4), PIPELINE_QUEUE_SIZE
+// (default 1000), PIPELINE_POLICY (default block), PIPELINE_SPILL_FILE
+// (default pipeline-spill.jsonl), PIPELINE_BATCH_SIZE (default 200) and
+// PIPELINE_FLUSH_INTERVAL (default 1s)
+func PipelineConfigFromEnv() PipelineConfig {
+	cfg := PipelineConfig{
+		Workers:       config.Int("PIPELINE_WORKERS", 4, 1),
+		QueueSize:     config.Int("PIPELINE_QUEUE_SIZE", 1000, 1),
+		Policy:        Policy(strings.ToLower(strings.TrimSpace(os.Getenv("PIPELINE_POLICY")))),
+		SpillPath:     os.Getenv("PIPELINE_SPILL_FILE"),
+		BatchSize:     config.Int("PIPELINE_BATCH_SIZE", 200, 1),
+		FlushInterval: time.Second,
+	}
+	if d, err := time.ParseDuration(os.Getenv("PIPELINE_FLUSH_INTERVAL")); err == nil && d > 0 {
+		cfg.FlushInterval = d
+	}
+	switch cfg.Policy {
+	case PolicyBlock, PolicyDropOldest, PolicySpill:
+	default:
+		cfg.Policy = PolicyBlock
+	}
+	if cfg.SpillPath == "" {
+		cfg.SpillPath = "pipeline-spill.jsonl"
+	}
+	return cfg
+}
+
+// Pipeline is a bounded queue between ingestion and storage drained by a pool
+// of workers, so fetches don't wait on database latency
+type Pipeline struct {
+	cfg   PipelineConfig
+	queue chan Item
+	spill *spillFile
+
+	// ProcessBatch stores a batch of metrics; it defaults to ProcessBatch
+	ProcessBatch func(ctx context.Context, batch []models.CampaignMetrics) ([]storage.WriteResult, []error)
+
+	// mu is held for reading while submitting and for writing to set closed,
+	// so the queue is never closed under a sender
+	mu     sync.RWMutex
+	closed bool
+
+	workers     sync.WaitGroup
+	stopDrainer context.CancelFunc
+	drainerDone chan struct{}
+}
+
+// Pipeline counters, published at /debug/vars under "pipeline"
+var (
+	pipelineVars   = expvar.NewMap("pipeline")
+	statSubmitted  = new(expvar.Int)
+	statProcessed  = new(expvar.Int)
+	statFailed     = new(expvar.Int)
+	statDropped    = new(expvar.Int)
+	statSpilled    = new(expvar.Int)
+	statInserted   = new(expvar.Int)
+	statUpdated    = new(expvar.Int)
+	statSkipped    = new(expvar.Int)
+	activePipeline atomic.Pointer[Pipeline]
+)
+
+func init() {
+	pipelineVars.Set("submitted", statSubmitted)
+	pipelineVars.Set("processed", statProcessed)
+	pipelineVars.Set("failed", statFailed)
+	pipelineVars.Set("dropped", statDropped)
+	pipelineVars.Set("spilled", statSpilled)
+	pipelineVars.Set("inserted", statInserted)
+	pipelineVars.Set("updated", statUpdated)
+	pipelineVars.Set("skipped", statSkipped)
+	pipelineVars.Set("queue_depth", expvar.Func(func() interface{} {
+		if p := activePipeline.Load(); p != nil {
+			return len(p.queue)
+		}
+		return 0
+	}))
+	pipelineVars.Set("queue_capacity", expvar.Func(func() interface{} {
+		if p := activePipeline.Load(); p != nil {
+			return cap(p.queue)
+		}
+		return 0
+	}))
+}
+
+// NewPipeline builds a pipeline; call Start before submitting
+func NewPipeline(cfg PipelineConfig) *Pipeline {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	p := &Pipeline{cfg: cfg, queue: make(chan Item, cfg.QueueSize), ProcessBatch: ProcessBatch}
+	if cfg.Policy == PolicySpill {
+		// ids start from the clock so they never collide with lines a previous
+		// process left in the file
+		p.spill = &spillFile{
+			path:      cfg.SpillPath,
+			nextID:    uint64(time.Now().UnixNano()),
+			callbacks: make(map[uint64]func(storage.WriteResult, error)),
+		}
+	}
+	return p
+}
+
+// Start launches the workers and, for the spill policy, the spill drainer.
+// Workers keep storing queued metrics after ctx is cancelled; Close drains them.
+func (p *Pipeline) Start(ctx context.Context) {
+	activePipeline.Store(p)
+	workCtx := context.WithoutCancel(ctx)
+	for i := 0; i < p.cfg.Workers; i++ {
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			p.work(workCtx)
+		}()
+	}
+
+	if p.spill != nil {
+		drainCtx, cancel := context.WithCancel(workCtx)
+		p.stopDrainer = cancel
+		p.drainerDone = make(chan struct{})
+		go func() {
+			defer close(p.drainerDone)
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				// Only feed spilled rows back once the queue has room again
+				if len(p.queue) <= cap(p.queue)/2 {
+					if err := p.spill.drain(drainCtx, p.queue); err != nil && drainCtx.Err() == nil {
+						fmt.Printf("[PIPELINE] Failed to replay spill file: %v\n", err)
+					}
+				}
+				select {
+				case <-drainCtx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	fmt.Printf("[PIPELINE] Started %d worker(s), queue size %d, batch size %d, policy %s\n", p.cfg.Workers, p.cfg.QueueSize, p.cfg.BatchSize, p.cfg.Policy)
+}
+
+// work collects queued items into batches, writing one when it is full or
+// when FlushInterval has passed since its first item, until the queue is closed
+func (p *Pipeline) work(ctx context.Context) {
+	batch := make([]Item, 0, p.cfg.BatchSize)
+	flushTimer := time.NewTimer(p.cfg.FlushInterval)
+	flushTimer.Stop()
+
+	flush := func() {
+		flushTimer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		metrics := make([]models.CampaignMetrics, len(batch))
+		for i, item := range batch {
+			metrics[i] = item.Metric
+		}
+		results, errs := p.ProcessBatch(ctx, metrics)
+		for i, item := range batch {
+			var err error
+			if errs != nil {
+				err = errs[i]
+			}
+			if err != nil {
+				statFailed.Add(1)
+			} else {
+				statProcessed.Add(1)
+			}
+			if item.Done == nil {
+				continue
+			}
+			var res storage.WriteResult
+			if i < len(results) {
+				res = results[i]
+			}
+			item.Done(res, err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case item, ok := <-p.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) == 1 {
+				flushTimer.Reset(p.cfg.FlushInterval)
+			}
+			if len(batch) >= p.cfg.BatchSize {
+				flush()
+			}
+		case <-flushTimer.C:
+			flush()
+		}
+	}
+}
+
+// Submit queues a metric according to the pipeline's policy. With the block
+// policy it returns ctx.Err() if ctx ends first, and after Close it returns
+// ErrClosed; Done is not called in either case.
+func (p *Pipeline) Submit(ctx context.Context, item Item) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	statSubmitted.Add(1)
+	select {
+	case p.queue <- item:
+		return nil
+	default:
+	}
+
+	switch p.cfg.Policy {
+	case PolicyDropOldest:
+		// Evict one item at a time and retry the send, so a slot freed by the
+		// eviction (or by a worker) isn't followed by a second eviction
+		for {
+			select {
+			case old := <-p.queue:
+				statDropped.Add(1)
+				fmt.Printf("[PIPELINE] Queue full, dropped metric for %s\n", old.Metric.CampaignID)
+				if old.Done != nil {
+					old.Done(storage.WriteSkipped, ErrDropped)
+				}
+			default:
+			}
+			select {
+			case p.queue <- item:
+				return nil
+			default:
+			}
+		}
+	case PolicySpill:
+		err := p.spill.write(item)
+		if err == nil {
+			statSpilled.Add(1)
+			return nil
+		}
+		fmt.Printf("[PIPELINE] Failed to spill metric, blocking instead: %v\n", err)
+	}
+
+	select {
+	case p.queue <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting work and waits until every queued and spilled metric
+// has been processed or ctx ends. Submits still running or arriving later,
+// e.g. from fetches that outlived the shutdown timeout, get ErrClosed.
+func (p *Pipeline) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		if p.spill != nil {
+			p.stopDrainer()
+			<-p.drainerDone
+			if err := p.spill.drain(ctx, p.queue); err != nil {
+				fmt.Printf("[PIPELINE] Failed to replay spill file: %v\n", err)
+			}
+		}
+		close(p.queue)
+		p.workers.Wait()
+	}()
+
+	select {
+	case <-done:
+		fmt.Println("[PIPELINE] Drained")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pipeline: %d metric(s) still queued: %w", len(p.queue), ctx.Err())
+	}
+}
+
+// countWrites adds write outcomes to the published inserted/updated/skipped
+// counters and returns their tally
+func countWrites(results ...storage.WriteResult) storage.WriteCounts {
+	counts := storage.CountWrites(results)
+	statInserted.Add(int64(counts.Inserted))
+	statUpdated.Add(int64(counts.Updated))
+	statSkipped.Add(int64(counts.Skipped))
+	return counts
+}
+
+// Default is the pipeline used by Submit; nil means metrics are processed inline
+var Default *Pipeline
+
+// Submit hands a metric to the Default pipeline, or processes it synchronously
+// when no pipeline is running. done may be nil.
+func Submit(ctx context.Context, m models.CampaignMetrics, done func(storage.WriteResult, error)) error {
+	if Default == nil {
+		res, err := ProcessMetric(ctx, m)
+		if done != nil {
+			done(res, err)
+		}
+		return nil
+	}
+	return Default.Submit(ctx, Item{Metric: m, Done: done})
+}
+
+// spillFile holds overflow metrics as JSON lines. Done callbacks can't be
+// written to disk, so they wait in memory under the line's id; lines left over
+// from a previous process are replayed without one.
+type spillFile struct {
+	path      string
+	mu        sync.Mutex
+	nextID    uint64
+	callbacks map[uint64]func(storage.WriteResult, error)
+}
+
+type spillRecord struct {
+	ID     uint64                 `json:"id"`
+	Metric models.CampaignMetrics `json:"metric"`
+}
+
+func (s *spillFile) write(item Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s.nextID++
+	if err := json.NewEncoder(f).Encode(spillRecord{ID: s.nextID, Metric: item.Metric}); err != nil {
+		return err
+	}
+	if item.Done != nil {
+		s.callbacks[s.nextID] = item.Done
+	}
+	return nil
+}
+
+// drain moves the spill file aside and feeds its records into queue. A
+// half-replayed file from an earlier call or crash is finished first.
+func (s *spillFile) drain(ctx context.Context, queue chan<- Item) error {
+	draining := s.path + ".draining"
+	if _, err := os.Stat(draining); os.IsNotExist(err) {
+		s.mu.Lock()
+		err := os.Rename(s.path, draining)
+		s.mu.Unlock()
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(draining)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec spillRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			fmt.Printf("[PIPELINE] Skipping corrupt spill line: %v\n", err)
+			continue
+		}
+		s.mu.Lock()
+		done := s.callbacks[rec.ID]
+		delete(s.callbacks, rec.ID)
+		s.mu.Unlock()
+
+		select {
+		case queue <- Item{Metric: rec.Metric, Done: done}:
+		case <-ctx.Done():
+			// Leave only this record and the unread ones for the next drain.
+			// Replaying a record that was already queued isn't harmless: in
+			// upsert mode it could overwrite a newer fetch of the same row.
+			if done != nil {
+				s.mu.Lock()
+				s.callbacks[rec.ID] = done
+				s.mu.Unlock()
+			}
+			if err := keepUnqueued(draining, scanner); err != nil {
+				return fmt.Errorf("rewrite spill file: %w", err)
+			}
+			return ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(draining)
+}
+
+// keepUnqueued replaces the file at path with the scanner's current line and
+// every line it hasn't read yet
+func keepUnqueued(path string, scanner *bufio.Scanner) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for ok := true; ok; ok = scanner.Scan() {
+		w.Write(scanner.Bytes())
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
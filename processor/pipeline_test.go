@@ -0,0 +1,233 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"campaign-analytics/models"
+	"campaign-analytics/storage"
+)
+
+// blockedPipeline returns a one-worker pipeline whose worker stalls until
+// release is closed, recording every campaign it stores
+func blockedPipeline(cfg PipelineConfig) (p *Pipeline, release chan struct{}, stored func() []string) {
+	release = make(chan struct{})
+	var mu sync.Mutex
+	var ids []string
+	p = NewPipeline(cfg)
+	p.ProcessBatch = func(ctx context.Context, batch []models.CampaignMetrics) ([]storage.WriteResult, []error) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		results := make([]storage.WriteResult, len(batch))
+		for i, m := range batch {
+			ids = append(ids, m.CampaignID)
+			results[i] = storage.WriteInserted
+		}
+		return results, nil
+	}
+	return p, release, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ids...)
+	}
+}
+
+// submitAll submits cmp-0..cmp-n-1 and collects the error each Done receives
+func submitAll(t *testing.T, p *Pipeline, n int) (results map[string]error, wait func()) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results = make(map[string]error)
+	for i := 0; i < n; i++ {
+		id := "cmp-" + string(rune('0'+i))
+		wg.Add(1)
+		err := p.Submit(context.Background(), Item{
+			Metric: models.CampaignMetrics{CampaignID: id},
+			Done: func(_ storage.WriteResult, err error) {
+				mu.Lock()
+				results[id] = err
+				mu.Unlock()
+				wg.Done()
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Let the worker pick up the first item so the queue fills predictably
+		if i == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	return results, wg.Wait
+}
+
+func TestPipelineDropOldest(t *testing.T) {
+	p, release, stored := blockedPipeline(PipelineConfig{Workers: 1, QueueSize: 2, Policy: PolicyDropOldest})
+	p.Start(context.Background())
+
+	// cmp-0 is in the worker, cmp-1 and cmp-2 fill the queue, then cmp-3 and
+	// cmp-4 evict them
+	results, wait := submitAll(t, p, 5)
+	close(release)
+	wait()
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"cmp-1", "cmp-2"} {
+		if !errors.Is(results[id], ErrDropped) {
+			t.Errorf("%s: err = %v, want ErrDropped", id, results[id])
+		}
+	}
+	if got := stored(); len(got) != 3 || got[0] != "cmp-0" || got[1] != "cmp-3" || got[2] != "cmp-4" {
+		t.Errorf("stored %v, want [cmp-0 cmp-3 cmp-4]", got)
+	}
+}
+
+func TestPipelineDropOldestEvictsOnePerSubmit(t *testing.T) {
+	// No workers run, so every submit past the second finds the queue full and
+	// must evict exactly one metric to make room
+	p := NewPipeline(PipelineConfig{Workers: 1, QueueSize: 2, Policy: PolicyDropOldest})
+	var dropped []string
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("cmp-%d", i)
+		err := p.Submit(context.Background(), Item{
+			Metric: models.CampaignMetrics{CampaignID: id},
+			Done: func(_ storage.WriteResult, err error) {
+				if errors.Is(err, ErrDropped) {
+					dropped = append(dropped, id)
+				}
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(dropped) != 98 || dropped[0] != "cmp-0" || dropped[97] != "cmp-97" {
+		t.Errorf("dropped %d metrics (%v), want cmp-0..cmp-97", len(dropped), dropped)
+	}
+	if len(p.queue) != 2 {
+		t.Fatalf("queue holds %d metrics, want 2", len(p.queue))
+	}
+	if a, b := <-p.queue, <-p.queue; a.Metric.CampaignID != "cmp-98" || b.Metric.CampaignID != "cmp-99" {
+		t.Errorf("queue holds %s, %s; want cmp-98, cmp-99", a.Metric.CampaignID, b.Metric.CampaignID)
+	}
+}
+
+func TestPipelineSpillsAndReplays(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), "spill.jsonl")
+	p, release, stored := blockedPipeline(PipelineConfig{Workers: 1, QueueSize: 1, Policy: PolicySpill, SpillPath: spill})
+	p.Start(context.Background())
+
+	results, wait := submitAll(t, p, 4)
+	close(release)
+	wait()
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("got %d callbacks, want 4", len(results))
+	}
+	for id, err := range results {
+		if err != nil {
+			t.Errorf("%s: err = %v", id, err)
+		}
+	}
+	if got := stored(); len(got) != 4 {
+		t.Errorf("stored %v, want all 4 metrics", got)
+	}
+
+	// A drain cancelled while the queue is full must leave exactly the
+	// records it didn't queue, so none is replayed a second time
+	sf := &spillFile{path: spill, callbacks: make(map[uint64]func(storage.WriteResult, error))}
+	for _, id := range []string{"cmp-a", "cmp-b", "cmp-c"} {
+		if err := sf.write(Item{Metric: models.CampaignMetrics{CampaignID: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue := make(chan Item, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := sf.drain(ctx, queue); !errors.Is(err, context.Canceled) {
+		t.Fatalf("drain err = %v, want context.Canceled", err)
+	}
+	if first := <-queue; first.Metric.CampaignID != "cmp-a" {
+		t.Fatalf("queued %s first, want cmp-a", first.Metric.CampaignID)
+	}
+
+	queue = make(chan Item, 3)
+	if err := sf.drain(context.Background(), queue); err != nil {
+		t.Fatal(err)
+	}
+	close(queue)
+	var replayed []string
+	for item := range queue {
+		replayed = append(replayed, item.Metric.CampaignID)
+	}
+	if len(replayed) != 2 || replayed[0] != "cmp-b" || replayed[1] != "cmp-c" {
+		t.Errorf("second drain replayed %v, want [cmp-b cmp-c]", replayed)
+	}
+}
+
+func TestDeadLetterClaimAndRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	saved := DeadLetterPath
+	DeadLetterPath = path
+	t.Cleanup(func() { DeadLetterPath = saved })
+	deadLetter([]models.CampaignMetrics{{CampaignID: "cmp-a"}, {CampaignID: "cmp-b"}}, errors.New("db down"), 3)
+
+	entries, claimed, err := ClaimDeadLetters(path)
+	if err != nil || len(entries) != 2 || entries[0].Attempts != 3 || entries[1].Error != "db down" {
+		t.Fatalf("claimed %+v, err %v", entries, err)
+	}
+	// A failure while the file is claimed goes to a fresh live file
+	deadLetter([]models.CampaignMetrics{{CampaignID: "cmp-c"}}, errors.New("db down"), 3)
+
+	if err := ReleaseDeadLetters(path, claimed, entries[1:]); err != nil {
+		t.Fatal(err)
+	}
+	left, err := ReadDeadLetters(path)
+	if err != nil || len(left) != 2 || left[0].Metric.CampaignID != "cmp-c" || left[1].Metric.CampaignID != "cmp-b" {
+		t.Fatalf("left %+v, err %v; want cmp-c then cmp-b", left, err)
+	}
+}
+
+func TestPipelineSubmitDuringClose(t *testing.T) {
+	p, release, _ := blockedPipeline(PipelineConfig{Workers: 2, QueueSize: 4, Policy: PolicyBlock})
+	close(release)
+	p.Start(context.Background())
+
+	// Fetches detached from shutdown keep submitting while Close runs; they
+	// must get ErrClosed instead of sending on the closed queue
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := p.Submit(context.Background(), Item{Metric: models.CampaignMetrics{CampaignID: "cmp"}})
+				if errors.Is(err, ErrClosed) {
+					return
+				} else if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,126 @@
+// storage/batch.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"campaign-analytics/models"
+)
+
+// batchChunkSize is the most rows sent in one INSERT. Each row uses 15 bind
+// parameters and Postgres allows 65535 per statement.
+const batchChunkSize = 1000
+
+// batchColumns are the bound columns of one row, with their casts so the
+// VALUES list types correctly even when the first row has NULLs
+var batchColumns = []string{"int", "text", "text", "text", "int", "int", "int", "numeric", "numeric", "timestamptz", "date", "text", "numeric", "numeric", "numeric"}
+
+// rowKey identifies a stored row: its campaign and the instant it starts
+type rowKey struct {
+	campaignID string
+	timestamp  int64
+}
+
+// InsertCampaignMetricsBatch writes many metrics with chunked multi-row
+// INSERT ... ON CONFLICT statements, following MetricsWriteMode like
+// InsertCampaignMetrics. results[i] is the outcome for metrics[i]. When a
+// metric appears twice in one batch, the later copy wins and the earlier one
+// is reported as skipped. On error, chunks before the failing one stay written;
+// the error wraps ErrConstraint or ErrTransient like InsertCampaignMetrics.
+func InsertCampaignMetricsBatch(ctx context.Context, metrics []models.CampaignMetrics) ([]WriteResult, error) {
+	results := make([]WriteResult, len(metrics))
+
+	// ON CONFLICT can't touch the same row twice in one statement, so keep
+	// only the last copy of each (campaign_id, timestamp)
+	last := make(map[rowKey]int, len(metrics))
+	for i, m := range metrics {
+		last[rowKey{m.CampaignID, m.Timestamp.UnixNano()}] = i
+	}
+	var unique []int
+	for i, m := range metrics {
+		if last[rowKey{m.CampaignID, m.Timestamp.UnixNano()}] == i {
+			unique = append(unique, i)
+		}
+	}
+
+	for start := 0; start < len(unique); start += batchChunkSize {
+		end := start + batchChunkSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		if err := insertChunk(ctx, metrics, unique[start:end], results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// insertChunk writes metrics[idx...] in one statement and fills in their results.
+// The input rows carry their batch index so the rows RETURNING reports back
+// can be matched to them.
+func insertChunk(ctx context.Context, metrics []models.CampaignMetrics, idx []int, results []WriteResult) error {
+	var values strings.Builder
+	args := make([]interface{}, 0, len(idx)*len(batchColumns))
+	for n, i := range idx {
+		if n > 0 {
+			values.WriteString(", ")
+		}
+		values.WriteString("(")
+		for c, typ := range batchColumns {
+			if c > 0 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "$%d::%s", len(args)+c+1, typ)
+		}
+		values.WriteString(")")
+
+		m := metrics[i]
+		args = append(args, i, m.CampaignID, m.Platform, nullIfEmpty(m.AccountID),
+			m.Impressions, m.Clicks, m.Conversions, m.Cost, m.Revenue, m.Timestamp, nullIfEmpty(m.Date),
+			nullIfEmpty(m.Currency), m.OriginalCost, m.OriginalRevenue, nullIfZero(m.FXRate))
+	}
+
+	conflict := "DO NOTHING"
+	if MetricsWriteMode == WriteModeUpsert {
+		conflict = upsertConflictClause
+	}
+	query := `WITH input (idx, campaign_id, platform, account_id, impressions, clicks, conversions, cost, revenue, timestamp, date,
+				currency, original_cost, original_revenue, fx_rate) AS (
+			VALUES ` + values.String() + `
+		), written AS (
+			INSERT INTO campaign_metrics
+				(campaign_id, platform, account_id, impressions, clicks, conversions, cost, revenue, timestamp, date,
+				currency, original_cost, original_revenue, fx_rate)
+			SELECT campaign_id, platform, account_id, impressions, clicks, conversions, cost, revenue, timestamp, date,
+				currency, original_cost, original_revenue, fx_rate
+			FROM input
+			ON CONFLICT (campaign_id, timestamp) ` + conflict + `
+			RETURNING campaign_id, timestamp, (xmax = 0) AS inserted
+		)
+		SELECT input.idx, written.inserted
+		FROM written JOIN input USING (campaign_id, timestamp)`
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer rows.Close()
+
+	// Rows not returned were left alone by the conflict clause; the zero
+	// value of results is already WriteSkipped
+	for rows.Next() {
+		var i int
+		var inserted bool
+		if err := rows.Scan(&i, &inserted); err != nil {
+			return classifyError(err)
+		}
+		if inserted {
+			results[i] = WriteInserted
+		} else {
+			results[i] = WriteUpdated
+		}
+	}
+	return classifyError(rows.Err())
+}
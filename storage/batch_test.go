@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+// benchDB connects to TEST_DATABASE_URL, a database with init.sql applied,
+// and skips the benchmark when it is unset
+func benchDB(b *testing.B) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		b.Fatal(err)
+	}
+	DB = db
+	b.Cleanup(func() {
+		db.Exec(`DELETE FROM campaign_metrics WHERE campaign_id LIKE 'bench-%'`)
+		db.Close()
+	})
+}
+
+// benchMetrics returns n distinct campaign-days under a unique campaign prefix
+func benchMetrics(n int) []models.CampaignMetrics {
+	prefix := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	metrics := make([]models.CampaignMetrics, n)
+	for i := range metrics {
+		metrics[i] = models.CampaignMetrics{
+			CampaignID:  fmt.Sprintf("%s-%d", prefix, i%50),
+			Platform:    "meta",
+			Impressions: 1000 + i,
+			Clicks:      50,
+			Conversions: 3,
+			Cost:        12_500_000,
+			Revenue:     40 * models.MicrosPerUnit,
+			Timestamp:   day.AddDate(0, 0, i/50),
+			Date:        day.AddDate(0, 0, i/50).Format("2006-01-02"),
+		}
+	}
+	return metrics
+}
+
+const benchRows = 1000
+
+func BenchmarkInsertPerRow(b *testing.B) {
+	benchDB(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metrics := benchMetrics(benchRows)
+		for _, m := range metrics {
+			if _, err := InsertCampaignMetrics(ctx, m); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchRows)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	benchDB(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metrics := benchMetrics(benchRows)
+		if _, err := InsertCampaignMetricsBatch(ctx, metrics); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchRows)/b.Elapsed().Seconds(), "rows/s")
+}
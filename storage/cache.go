@@ -9,10 +9,9 @@ import (
 )
 
 var RedisClient *redis.Client
-var ctx = context.Background()
 
 // InitRedis connects to the Redis server
-func InitRedis() error {
+func InitRedis(ctx context.Context) error {
 	RedisClient = redis.NewClient(&redis.Options{
 		Addr:     "redis:6379",
 		Password: "", // no password set
@@ -29,11 +28,11 @@ func InitRedis() error {
 }
 
 // SetCache stores a key-value pair with TTL in Redis
-func SetCache(key string, value string, ttl time.Duration) error {
+func SetCache(ctx context.Context, key string, value string, ttl time.Duration) error {
 	return RedisClient.Set(ctx, key, value, ttl).Err()
 }
 
 // GetCache retrieves the value for a given key from Redis
-func GetCache(key string) (string, error) {
+func GetCache(ctx context.Context, key string) (string, error) {
 	return RedisClient.Get(ctx, key).Result()
 }
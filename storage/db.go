@@ -2,8 +2,12 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"campaign-analytics/models"
 
@@ -13,15 +17,48 @@ import (
 // DB is a shared global database connection object
 var DB *sql.DB
 
+// WriteMode controls what happens when a metric arrives for a (campaign_id, timestamp)
+// that is already stored
+type WriteMode string
+
+const (
+	// WriteModeInsert keeps the first stored value and ignores later ones
+	WriteModeInsert WriteMode = "insert"
+	// WriteModeUpsert overwrites with the latest value and bumps the row revision
+	WriteModeUpsert WriteMode = "upsert"
+)
+
+// WriteResult is what a single metrics write did to the stored row
+type WriteResult int
+
+const (
+	// WriteSkipped means the row already existed and was left unchanged (deduped)
+	WriteSkipped WriteResult = iota
+	// WriteInserted means a new row was stored
+	WriteInserted
+	// WriteUpdated means an existing row was restated with new values
+	WriteUpdated
+)
+
+// MetricsWriteMode is read from METRICS_WRITE_MODE and defaults to insert
+var MetricsWriteMode = parseWriteMode(os.Getenv("METRICS_WRITE_MODE"))
+
+func parseWriteMode(s string) WriteMode {
+	if WriteMode(strings.ToLower(strings.TrimSpace(s))) == WriteModeUpsert {
+		return WriteModeUpsert
+	}
+	return WriteModeInsert
+}
+
 // InitDB initializes the PostgreSQL connection
-func InitDB() error {
+func InitDB(ctx context.Context) error {
 	connStr := "host=postgres port=5432 user=postgres dbname=campaigns password=postgres sslmode=disable"
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return err
 	}
 
-	err = db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -31,28 +68,102 @@ func InitDB() error {
 	return nil
 }
 
-// InsertCampaignMetrics inserts a metrics record into the DB
-func InsertCampaignMetrics(m models.CampaignMetrics) error {
-	query := `INSERT INTO campaign_metrics
-		(campaign_id, platform, impressions, clicks, conversions, cost, revenue, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (campaign_id, timestamp) DO NOTHING`
+const insertMetricsQuery = `INSERT INTO campaign_metrics
+		(campaign_id, platform, account_id, impressions, clicks, conversions, cost, revenue, timestamp, date,
+			currency, original_cost, original_revenue, fx_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (campaign_id, timestamp) DO NOTHING
+		RETURNING (xmax = 0)`
+
+// upsertConflictClause overwrites restated values. Re-polls that return identical
+// values leave the row (and its revision) untouched; the WHERE tuple must list
+// every column the SET changes, or a restated currency or FX rate is dropped.
+const upsertConflictClause = `DO UPDATE SET
+			platform = EXCLUDED.platform,
+			account_id = EXCLUDED.account_id,
+			impressions = EXCLUDED.impressions,
+			clicks = EXCLUDED.clicks,
+			conversions = EXCLUDED.conversions,
+			cost = EXCLUDED.cost,
+			revenue = EXCLUDED.revenue,
+			date = EXCLUDED.date,
+			currency = EXCLUDED.currency,
+			original_cost = EXCLUDED.original_cost,
+			original_revenue = EXCLUDED.original_revenue,
+			fx_rate = EXCLUDED.fx_rate,
+			revision = campaign_metrics.revision + 1,
+			restated_at = NOW()
+		WHERE (campaign_metrics.platform, campaign_metrics.account_id, campaign_metrics.impressions,
+				campaign_metrics.clicks, campaign_metrics.conversions, campaign_metrics.cost, campaign_metrics.revenue,
+				campaign_metrics.date, campaign_metrics.currency, campaign_metrics.original_cost,
+				campaign_metrics.original_revenue, campaign_metrics.fx_rate)
+			IS DISTINCT FROM (EXCLUDED.platform, EXCLUDED.account_id, EXCLUDED.impressions,
+				EXCLUDED.clicks, EXCLUDED.conversions, EXCLUDED.cost, EXCLUDED.revenue,
+				EXCLUDED.date, EXCLUDED.currency, EXCLUDED.original_cost,
+				EXCLUDED.original_revenue, EXCLUDED.fx_rate)`
+
+const upsertMetricsQuery = `INSERT INTO campaign_metrics
+		(campaign_id, platform, account_id, impressions, clicks, conversions, cost, revenue, timestamp, date,
+			currency, original_cost, original_revenue, fx_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (campaign_id, timestamp) ` + upsertConflictClause + `
+		RETURNING (xmax = 0)`
 
-	_, err := DB.Exec(query,
+// InsertCampaignMetrics writes a metrics record into the DB according to MetricsWriteMode
+// and reports whether the row was inserted, updated or skipped as a duplicate.
+// Failures wrap ErrConstraint or ErrTransient when the cause is known.
+func InsertCampaignMetrics(ctx context.Context, m models.CampaignMetrics) (WriteResult, error) {
+	query := insertMetricsQuery
+	if MetricsWriteMode == WriteModeUpsert {
+		query = upsertMetricsQuery
+	}
+
+	// xmax is 0 only for freshly inserted rows; no row comes back when the
+	// conflict clause left the stored row alone
+	var inserted bool
+	err := DB.QueryRowContext(ctx, query,
 		m.CampaignID,
 		m.Platform,
+		nullIfEmpty(m.AccountID),
 		m.Impressions,
 		m.Clicks,
 		m.Conversions,
 		m.Cost,
 		m.Revenue,
 		m.Timestamp,
-	)
+		nullIfEmpty(m.Date),
+		nullIfEmpty(m.Currency),
+		m.OriginalCost,
+		m.OriginalRevenue,
+		nullIfZero(m.FXRate),
+	).Scan(&inserted)
 
 	// Return nil if the insert was skipped due to duplication
-	if err != nil && err.Error() == "pq: duplicate key value violates unique constraint \"campaign_metrics_campaign_id_timestamp_key\"" {
+	err = classifyError(err)
+	if err == sql.ErrNoRows || errors.Is(err, ErrDuplicate) {
+		return WriteSkipped, nil
+	}
+	if err != nil {
+		return WriteSkipped, err
+	}
+	if inserted {
+		return WriteInserted, nil
+	}
+	return WriteUpdated, nil
+}
+
+// nullIfZero maps 0 to SQL NULL for optional numeric columns
+func nullIfZero(f float64) interface{} {
+	if f == 0 {
 		return nil
 	}
+	return f
+}
 
-	return err
+// nullIfEmpty maps "" to SQL NULL for optional text columns
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
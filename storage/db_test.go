@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUpsertComparesEverySetColumn(t *testing.T) {
+	set, where, _ := strings.Cut(upsertConflictClause, "WHERE")
+	current, excluded, _ := strings.Cut(where, "IS DISTINCT FROM")
+
+	for _, m := range regexp.MustCompile(`(\w+) = EXCLUDED\.`).FindAllStringSubmatch(set, -1) {
+		col := m[1]
+		if !strings.Contains(current, "campaign_metrics."+col) || !strings.Contains(excluded, "EXCLUDED."+col) {
+			t.Errorf("%s is updated but not compared, so a restatement of only %s is dropped", col, col)
+		}
+	}
+}
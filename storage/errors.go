@@ -0,0 +1,98 @@
+// storage/errors.go
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Error kinds returned by storage writes. Match them with errors.Is; the
+// original driver error stays wrapped underneath.
+var (
+	// ErrDuplicate is a unique constraint violation (SQLSTATE 23505)
+	ErrDuplicate = errors.New("duplicate row")
+	// ErrConstraint is any other integrity or data error (SQLSTATE classes 22
+	// and 23); retrying the same row will fail again
+	ErrConstraint = errors.New("constraint violation")
+	// ErrTransient is a connection, resource or concurrency failure worth retrying
+	ErrTransient = errors.New("transient database error")
+)
+
+// transientCodes are SQLSTATEs outside the transient classes that still succeed on retry
+var transientCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"57014": true, // query_canceled (statement timeout)
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// classifyError wraps err with the matching kind. Errors of unknown kind and
+// context cancellations are returned unchanged.
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505":
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		case pqErr.Code.Class() == "23", pqErr.Code.Class() == "22":
+			return fmt.Errorf("%w: %w", ErrConstraint, err)
+		case pqErr.Code.Class() == "08", pqErr.Code.Class() == "53", transientCodes[pqErr.Code]:
+			return fmt.Errorf("%w: %w", ErrTransient, err)
+		}
+		return err
+	}
+
+	// Failures below the SQL layer: dropped or refused connections and timeouts
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrTransient, err)
+	}
+	return err
+}
+
+// WriteCounts tallies the outcomes of metric writes
+type WriteCounts struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// Add counts one write outcome
+func (c *WriteCounts) Add(r WriteResult) {
+	switch r {
+	case WriteInserted:
+		c.Inserted++
+	case WriteUpdated:
+		c.Updated++
+	default:
+		c.Skipped++
+	}
+}
+
+// CountWrites tallies a batch of write outcomes
+func CountWrites(results []WriteResult) WriteCounts {
+	var c WriteCounts
+	for _, r := range results {
+		c.Add(r)
+	}
+	return c
+}
+
+func (c WriteCounts) String() string {
+	return fmt.Sprintf("%d inserted, %d updated, %d skipped", c.Inserted, c.Updated, c.Skipped)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{&pq.Error{Code: "23505"}, ErrDuplicate},
+		{&pq.Error{Code: "23502"}, ErrConstraint}, // not_null_violation
+		{&pq.Error{Code: "22003"}, ErrConstraint}, // numeric_value_out_of_range
+		{&pq.Error{Code: "08006"}, ErrTransient},  // connection_failure
+		{&pq.Error{Code: "40P01"}, ErrTransient},  // deadlock_detected
+		{&pq.Error{Code: "53300"}, ErrTransient},  // too_many_connections
+		{&pq.Error{Code: "57P01"}, ErrTransient},  // admin_shutdown
+		{fmt.Errorf("query: %w", driver.ErrBadConn), ErrTransient},
+		{io.ErrUnexpectedEOF, ErrTransient},
+	}
+	for _, c := range cases {
+		got := classifyError(c.err)
+		if !errors.Is(got, c.kind) || !errors.Is(got, c.err) {
+			t.Errorf("classifyError(%v) = %v, want %v wrapping the original", c.err, got, c.kind)
+		}
+	}
+
+	for _, err := range []error{&pq.Error{Code: "42P01"}, context.Canceled, errors.New("other")} {
+		got := classifyError(err)
+		if errors.Is(got, ErrTransient) || errors.Is(got, ErrConstraint) || errors.Is(got, ErrDuplicate) {
+			t.Errorf("classifyError(%v) = %v, want it left unclassified", err, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// storage/rollup.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"campaign-analytics/metrics"
+
+	"github.com/lib/pq"
+)
+
+// rollupDimensions maps each dimension rows can be grouped or filtered by to its column
+var rollupDimensions = map[string]string{
+	"platform": "platform",
+	"campaign": "campaign_id",
+	"account":  "COALESCE(account_id, '')",
+	"day":      "to_char(" + reportingDayExpr + ", 'YYYY-MM-DD')",
+}
+
+// rollupOrders are the figures a rollup can be ordered by. Ratios follow
+// metrics.Compute and are NULL when their denominator is zero.
+var rollupOrders = map[string]string{
+	"impressions": "SUM(impressions)",
+	"clicks":      "SUM(clicks)",
+	"conversions": "SUM(conversions)",
+	"cost":        "SUM(cost)",
+	"revenue":     "SUM(revenue)",
+	"profit":      "COALESCE(SUM(revenue), 0) - COALESCE(SUM(cost), 0)",
+	"ctr":         "SUM(clicks)::numeric / NULLIF(SUM(impressions), 0)",
+	"cpc":         "SUM(cost) / NULLIF(SUM(clicks), 0)",
+	"cpm":         "SUM(cost) * 1000 / NULLIF(SUM(impressions), 0)",
+	"cvr":         "SUM(conversions)::numeric / NULLIF(SUM(clicks), 0)",
+	"cpa":         "SUM(cost) / NULLIF(SUM(conversions), 0)",
+	"roas":        "SUM(revenue) / NULLIF(SUM(cost), 0)",
+	"aov":         "SUM(revenue) / NULLIF(SUM(conversions), 0)",
+}
+
+// maxRollupLimit caps how many groups one rollup returns
+const maxRollupLimit = 1000
+
+// RollupQuery sums metrics across campaigns, grouped by one or more dimensions
+// (platform, campaign, account, day). From and To are the first and last
+// reporting days included; Location is used for rows without a reporting date.
+type RollupQuery struct {
+	GroupBy  []string
+	Filters  map[string][]string // dimension -> values to keep
+	Location *time.Location
+	From, To time.Time
+	OrderBy  string // a figure such as "cost", or a grouped dimension; default the dimensions
+	Desc     bool
+	Limit    int
+}
+
+// RollupRow is the summed metrics of one group
+type RollupRow struct {
+	Group  map[string]string
+	Totals metrics.Totals
+}
+
+// Validate reports the first invalid part of q
+func (q RollupQuery) Validate() error {
+	if len(q.GroupBy) == 0 {
+		return fmt.Errorf("group_by needs at least one of platform, campaign, account, day")
+	}
+	seen := make(map[string]bool)
+	for _, dim := range q.GroupBy {
+		if rollupDimensions[dim] == "" {
+			return fmt.Errorf("cannot group by %q: use platform, campaign, account or day", dim)
+		}
+		if seen[dim] {
+			return fmt.Errorf("group_by lists %q twice", dim)
+		}
+		seen[dim] = true
+	}
+	for dim := range q.Filters {
+		if rollupDimensions[dim] == "" {
+			return fmt.Errorf("cannot filter by %q: use platform, campaign, account or day", dim)
+		}
+	}
+	if q.OrderBy != "" && rollupOrders[q.OrderBy] == "" && !seen[q.OrderBy] {
+		return fmt.Errorf("cannot order by %q: use a metric such as cost or roas, or a grouped dimension", q.OrderBy)
+	}
+	if q.Limit < 0 || q.Limit > maxRollupLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxRollupLimit)
+	}
+	if q.To.Before(q.From) {
+		return fmt.Errorf("from must not be after to")
+	}
+	return nil
+}
+
+// Rollup runs q as one GROUP BY query
+func Rollup(ctx context.Context, q RollupQuery) ([]RollupRow, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := dayRangeArgs(q.Location, q.From, q.To)
+	where := " WHERE " + reportingDayRange
+	for dim, values := range q.Filters {
+		args = append(args, pq.Array(values))
+		where += fmt.Sprintf(" AND %s = ANY($%d)", rollupDimensions[dim], len(args))
+	}
+
+	groups := make([]string, len(q.GroupBy))
+	for i := range q.GroupBy {
+		groups[i] = fmt.Sprint(i + 1)
+	}
+	dimOrder := strings.Join(groups, ", ")
+
+	order := dimOrder
+	if q.OrderBy != "" {
+		dir := "ASC"
+		if q.Desc {
+			dir = "DESC"
+		}
+		expr := rollupOrders[q.OrderBy]
+		for i, dim := range q.GroupBy {
+			if dim == q.OrderBy {
+				expr = groups[i]
+			}
+		}
+		// Ties and undefined ratios fall back to the dimension order
+		order = fmt.Sprintf("%s %s NULLS LAST, %s", expr, dir, dimOrder)
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = 100
+	}
+
+	columns := make([]string, len(q.GroupBy))
+	for i, dim := range q.GroupBy {
+		columns[i] = rollupDimensions[dim]
+	}
+	query := `SELECT ` + strings.Join(columns, ", ") + `, ` + totalsColumns + `
+			FROM campaign_metrics` + where + `
+			GROUP BY ` + dimOrder + `
+			ORDER BY ` + order + `
+			LIMIT ` + fmt.Sprint(limit)
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RollupRow
+	for rows.Next() {
+		keys := make([]string, len(q.GroupBy))
+		var t metrics.Totals
+		dest := make([]interface{}, len(keys))
+		for i := range keys {
+			dest[i] = &keys[i]
+		}
+		if err := rows.Scan(append(dest, totalsDest(&t)...)...); err != nil {
+			return nil, err
+		}
+
+		row := RollupRow{Group: make(map[string]string, len(keys)), Totals: t}
+		for i, dim := range q.GroupBy {
+			row.Group[dim] = keys[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,27 @@
+package storage
+
+import "testing"
+
+func TestRollupQueryValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		q    RollupQuery
+		ok   bool
+	}{
+		{"top campaigns by spend", RollupQuery{GroupBy: []string{"campaign"}, OrderBy: "cost", Limit: 10}, true},
+		{"order by grouped dimension", RollupQuery{GroupBy: []string{"day", "platform"}, OrderBy: "day"}, true},
+		{"filter by account", RollupQuery{GroupBy: []string{"platform"}, Filters: map[string][]string{"account": {"act_1"}}}, true},
+		{"no dimensions", RollupQuery{}, false},
+		{"unknown dimension", RollupQuery{GroupBy: []string{"country"}}, false},
+		{"dimension twice", RollupQuery{GroupBy: []string{"platform", "platform"}}, false},
+		{"unknown filter", RollupQuery{GroupBy: []string{"platform"}, Filters: map[string][]string{"country": {"DE"}}}, false},
+		// Ordering by a dimension that isn't grouped would be ambiguous
+		{"order by ungrouped dimension", RollupQuery{GroupBy: []string{"platform"}, OrderBy: "day"}, false},
+		{"limit too large", RollupQuery{GroupBy: []string{"platform"}, Limit: maxRollupLimit + 1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.q.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok=%t", tt.name, err, tt.ok)
+		}
+	}
+}
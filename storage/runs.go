@@ -0,0 +1,159 @@
+// storage/runs.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"campaign-analytics/models"
+)
+
+// InsertIngestionRun stores one ingestion run and returns its id
+func InsertIngestionRun(ctx context.Context, r models.IngestionRun) (int64, error) {
+	var id int64
+	err := DB.QueryRowContext(ctx, `INSERT INTO ingestion_runs
+		(source, account_id, window_from, window_to, started_at, finished_at,
+		 rows_fetched, rows_inserted, rows_updated, rows_deduped, rows_failed, error, error_kind, error_code)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`,
+		r.Source,
+		r.AccountID,
+		r.WindowFrom,
+		r.WindowTo,
+		r.StartedAt,
+		r.FinishedAt,
+		r.RowsFetched,
+		r.RowsInserted,
+		r.RowsUpdated,
+		r.RowsDeduped,
+		r.RowsFailed,
+		nullIfEmpty(r.Error),
+		nullIfEmpty(r.ErrorKind),
+		nullIfEmpty(r.ErrorCode),
+	).Scan(&id)
+	return id, err
+}
+
+// RunFilter narrows ListIngestionRuns; zero values match everything
+type RunFilter struct {
+	Source    string
+	AccountID string
+	Status    string // "succeeded" or "failed"
+	Since     time.Time
+	Limit     int
+}
+
+// ListIngestionRuns returns the newest runs matching f
+func ListIngestionRuns(ctx context.Context, f RunFilter) ([]models.IngestionRun, error) {
+	query := `SELECT id, source, account_id, window_from, window_to, started_at, finished_at,
+			rows_fetched, rows_inserted, rows_updated, rows_deduped, rows_failed,
+			COALESCE(error, ''), COALESCE(error_kind, ''), COALESCE(error_code, '')
+			FROM ingestion_runs WHERE 1 = 1`
+	var args []interface{}
+	argIdx := 1
+
+	if f.Source != "" {
+		query += fmt.Sprintf(" AND source = $%d", argIdx)
+		args = append(args, f.Source)
+		argIdx++
+	}
+	if f.AccountID != "" {
+		query += fmt.Sprintf(" AND account_id = $%d", argIdx)
+		args = append(args, f.AccountID)
+		argIdx++
+	}
+	switch f.Status {
+	case "succeeded":
+		query += " AND error IS NULL"
+	case "failed":
+		query += " AND error IS NOT NULL"
+	}
+	if !f.Since.IsZero() {
+		query += fmt.Sprintf(" AND started_at >= $%d", argIdx)
+		args = append(args, f.Since)
+		argIdx++
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	query += fmt.Sprintf(" ORDER BY started_at DESC LIMIT $%d", argIdx)
+	args = append(args, limit)
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.IngestionRun
+	for rows.Next() {
+		var r models.IngestionRun
+		if err := rows.Scan(
+			&r.ID,
+			&r.Source,
+			&r.AccountID,
+			&r.WindowFrom,
+			&r.WindowTo,
+			&r.StartedAt,
+			&r.FinishedAt,
+			&r.RowsFetched,
+			&r.RowsInserted,
+			&r.RowsUpdated,
+			&r.RowsDeduped,
+			&r.RowsFailed,
+			&r.Error,
+			&r.ErrorKind,
+			&r.ErrorCode,
+		); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// LastSuccessfulSyncs returns, per source and account, the latest successful
+// run and any failure recorded after it. source may be empty for all sources.
+func LastSuccessfulSyncs(ctx context.Context, source string) ([]models.SourceSync, error) {
+	rows, err := DB.QueryContext(ctx, `SELECT s.source, s.account_id, s.finished_at, s.window_to,
+			s.rows_inserted + s.rows_updated,
+			f.finished_at, COALESCE(f.error, '')
+		FROM (
+			SELECT DISTINCT ON (source, account_id) *
+			FROM ingestion_runs
+			WHERE error IS NULL AND ($1 = '' OR source = $1)
+			ORDER BY source, account_id, finished_at DESC
+		) s
+		LEFT JOIN LATERAL (
+			SELECT finished_at, error FROM ingestion_runs f
+			WHERE f.source = s.source AND f.account_id = s.account_id
+				AND f.error IS NOT NULL AND f.finished_at > s.finished_at
+			ORDER BY f.finished_at DESC LIMIT 1
+		) f ON true
+		ORDER BY s.source, s.account_id`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var syncs []models.SourceSync
+	for rows.Next() {
+		var s models.SourceSync
+		if err := rows.Scan(
+			&s.Source,
+			&s.AccountID,
+			&s.LastSuccessAt,
+			&s.LastWindowTo,
+			&s.LastRowsStored,
+			&s.LastFailureAt,
+			&s.LastError,
+		); err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, s)
+	}
+	return syncs, rows.Err()
+}
@@ -0,0 +1,187 @@
+// storage/series.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"campaign-analytics/metrics"
+)
+
+// Interval is the width of a time-series bucket
+type Interval string
+
+const (
+	IntervalHour  Interval = "hour"
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week" // starting Monday
+	IntervalMonth Interval = "month"
+)
+
+// ParseInterval validates an interval name
+func ParseInterval(s string) (Interval, error) {
+	switch i := Interval(s); i {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return i, nil
+	}
+	return "", fmt.Errorf("interval must be hour, day, week or month")
+}
+
+// reportingDayExpr is a row's reporting day: its date column, or for rows
+// stored without one, the day of its timestamp in the timezone bound to $1
+const reportingDayExpr = "COALESCE(date, (timestamp AT TIME ZONE $1::text)::date)"
+
+// reportingDayRange matches rows whose reporting day is between $2 and $3. It
+// means reportingDayExpr BETWEEN $2 AND $3, but is written so both arms can use
+// an index: dated rows through date, undated ones through a timestamp bound
+// wide enough for any timezone offset.
+const reportingDayRange = `(date BETWEEN $2::date AND $3::date
+		OR (date IS NULL AND timestamp >= $2::date - 2 AND timestamp < $3::date + 3
+			AND (timestamp AT TIME ZONE $1::text)::date BETWEEN $2::date AND $3::date))`
+
+// dayRangeArgs returns the $1-$3 arguments of reportingDayRange. A zero from
+// or to leaves that end of the range open.
+func dayRangeArgs(loc *time.Location, from, to time.Time) []interface{} {
+	first, last := "0001-01-01", "9999-12-31"
+	if !from.IsZero() {
+		first = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		last = to.Format("2006-01-02")
+	}
+	return []interface{}{loc.String(), first, last}
+}
+
+// MaxSeriesBuckets bounds how many buckets one series may have
+const MaxSeriesBuckets = 2000
+
+// MetricFilter narrows metric queries; zero values match everything
+type MetricFilter struct {
+	CampaignID string
+	Platform   string
+	AccountID  string
+}
+
+// conditions appends f's SQL conditions to where, numbering placeholders after args
+func (f MetricFilter) conditions(where string, args []interface{}) (string, []interface{}) {
+	for _, c := range []struct{ column, value string }{
+		{"campaign_id", f.CampaignID},
+		{"platform", f.Platform},
+		{"account_id", f.AccountID},
+	} {
+		if c.value != "" {
+			args = append(args, c.value)
+			where += fmt.Sprintf(" AND %s = $%d", c.column, len(args))
+		}
+	}
+	return where, args
+}
+
+// SeriesQuery selects a bucketed time series. From and To are the first and
+// last days included, as calendar days in Location.
+type SeriesQuery struct {
+	MetricFilter
+	Interval Interval
+	Location *time.Location
+	From, To time.Time
+}
+
+// SeriesPoint is the summed metrics of one bucket
+type SeriesPoint struct {
+	Start  time.Time // in the query's Location
+	Totals metrics.Totals
+}
+
+// Buckets returns the start of every bucket the query covers. Week and month
+// buckets start on a Monday or the 1st, so the first one may begin before From.
+func (q SeriesQuery) Buckets() ([]time.Time, error) {
+	loc := q.Location
+	fy, fm, fd := q.From.Date()
+	ty, tm, td := q.To.Date()
+	from := time.Date(fy, fm, fd, 0, 0, 0, 0, loc)
+	end := time.Date(ty, tm, td+1, 0, 0, 0, 0, loc)
+	if !from.Before(end) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+
+	var step func(time.Time) time.Time
+	start := from
+	switch q.Interval {
+	case IntervalHour:
+		// Absolute hours, so DST changes give 23- or 25-hour days
+		step = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case IntervalDay:
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case IntervalWeek:
+		start = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case IntervalMonth:
+		start = time.Date(fy, fm, 1, 0, 0, 0, 0, loc)
+		step = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	default:
+		return nil, fmt.Errorf("unknown interval %q", q.Interval)
+	}
+
+	var buckets []time.Time
+	for t := start; t.Before(end); t = step(t) {
+		if len(buckets) == MaxSeriesBuckets {
+			return nil, fmt.Errorf("more than %d %s buckets; narrow the range or use a wider interval", MaxSeriesBuckets, q.Interval)
+		}
+		buckets = append(buckets, t)
+	}
+	return buckets, nil
+}
+
+// MetricSeries sums metrics per bucket, returning every bucket of the query in
+// order with zero totals where there are no rows. Day, week and month buckets
+// group rows by their reporting date, so a day reported in the account's
+// timezone stays whole; hour buckets use the row timestamp in Location.
+func MetricSeries(ctx context.Context, q SeriesQuery) ([]SeriesPoint, error) {
+	buckets, err := q.Buckets()
+	if err != nil {
+		return nil, err
+	}
+
+	// $1 is the timezone name throughout
+	args := []interface{}{q.Location.String()}
+	var bucket, where string
+	if q.Interval == IntervalHour {
+		bucket = "date_trunc('hour', timestamp, $1::text)"
+		end := time.Date(q.To.Year(), q.To.Month(), q.To.Day()+1, 0, 0, 0, 0, q.Location)
+		args = append(args, buckets[0], end)
+		where = " WHERE timestamp >= $2 AND timestamp < $3"
+	} else {
+		bucket = fmt.Sprintf("date_trunc('%s', %s::timestamp) AT TIME ZONE $1::text", q.Interval, reportingDayExpr)
+		args = append(args, q.From.Format("2006-01-02"), q.To.Format("2006-01-02"))
+		where = " WHERE " + reportingDayRange
+	}
+	where, args = q.conditions(where, args)
+
+	rows, err := DB.QueryContext(ctx, `SELECT `+bucket+` AS bucket, `+totalsColumns+`
+			FROM campaign_metrics`+where+`
+			GROUP BY bucket`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[int64]metrics.Totals)
+	for rows.Next() {
+		var start time.Time
+		var t metrics.Totals
+		if err := rows.Scan(append([]interface{}{&start}, totalsDest(&t)...)...); err != nil {
+			return nil, err
+		}
+		sums[start.Unix()] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	points := make([]SeriesPoint, len(buckets))
+	for i, start := range buckets {
+		points[i] = SeriesPoint{Start: start, Totals: sums[start.Unix()]}
+	}
+	return points, nil
+}
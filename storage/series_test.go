@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesBuckets(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	tests := []struct {
+		interval    Interval
+		from, to    string
+		count       int
+		first, last string
+	}{
+		{IntervalDay, "2024-03-09", "2024-03-11", 3, "2024-03-09T00:00:00-05:00", "2024-03-11T00:00:00-04:00"},
+		// Clocks go forward on 2024-03-10, so that day has 23 hours
+		{IntervalHour, "2024-03-10", "2024-03-10", 23, "2024-03-10T00:00:00-05:00", "2024-03-10T23:00:00-04:00"},
+		// 2024-03-13 is a Wednesday; weeks start on Monday
+		{IntervalWeek, "2024-03-13", "2024-03-25", 3, "2024-03-11T00:00:00-04:00", "2024-03-25T00:00:00-04:00"},
+		{IntervalMonth, "2024-01-31", "2024-03-01", 3, "2024-01-01T00:00:00-05:00", "2024-03-01T00:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		q := SeriesQuery{Interval: tt.interval, Location: ny, From: day(tt.from), To: day(tt.to)}
+		buckets, err := q.Buckets()
+		if err != nil {
+			t.Errorf("%s %s..%s: %v", tt.interval, tt.from, tt.to, err)
+			continue
+		}
+		if len(buckets) != tt.count ||
+			buckets[0].Format(time.RFC3339) != tt.first || buckets[len(buckets)-1].Format(time.RFC3339) != tt.last {
+			t.Errorf("%s %s..%s: %d buckets %s..%s, want %d %s..%s", tt.interval, tt.from, tt.to,
+				len(buckets), buckets[0].Format(time.RFC3339), buckets[len(buckets)-1].Format(time.RFC3339), tt.count, tt.first, tt.last)
+		}
+	}
+
+	if _, err := (SeriesQuery{Interval: IntervalHour, Location: time.UTC, From: day("2020-01-01"), To: day("2024-01-01")}).Buckets(); err == nil {
+		t.Error("four years of hours: want too many buckets error")
+	}
+	if _, err := (SeriesQuery{Interval: IntervalDay, Location: time.UTC, From: day("2024-01-02"), To: day("2024-01-01")}).Buckets(); err == nil {
+		t.Error("from after to: want error")
+	}
+}
@@ -0,0 +1,60 @@
+// storage/totals.go
+package storage
+
+import (
+	"context"
+	"time"
+
+	"campaign-analytics/metrics"
+	"campaign-analytics/models"
+)
+
+// totalsColumns sums the metrics.Totals components; scan them with totalsDest
+const totalsColumns = `COALESCE(SUM(impressions), 0), COALESCE(SUM(clicks), 0), COALESCE(SUM(conversions), 0),
+			COALESCE(SUM(cost), 0), COALESCE(SUM(revenue), 0)`
+
+// totalsDest returns the Scan destinations for totalsColumns
+func totalsDest(t *metrics.Totals) []interface{} {
+	return []interface{}{&t.Impressions, &t.Clicks, &t.Conversions, &t.Cost, &t.Revenue}
+}
+
+// MetricTotals sums the rows matching f whose reporting day is between from
+// and to (inclusive; a zero from or to leaves that end open). loc is the
+// timezone used for rows without a reporting date.
+func MetricTotals(ctx context.Context, f MetricFilter, loc *time.Location, from, to time.Time) (metrics.Totals, error) {
+	where, args := f.conditions(" WHERE "+reportingDayRange, dayRangeArgs(loc, from, to))
+
+	var t metrics.Totals
+	err := DB.QueryRowContext(ctx, `SELECT `+totalsColumns+` FROM campaign_metrics`+where, args...).Scan(totalsDest(&t)...)
+	return t, err
+}
+
+// LatestMetric returns the newest row among those MetricTotals would sum, or
+// sql.ErrNoRows if there is none
+func LatestMetric(ctx context.Context, f MetricFilter, loc *time.Location, from, to time.Time) (models.CampaignMetrics, error) {
+	where, args := f.conditions(" WHERE "+reportingDayRange, dayRangeArgs(loc, from, to))
+
+	var m models.CampaignMetrics
+	err := DB.QueryRowContext(ctx, `SELECT campaign_id, platform, COALESCE(account_id, ''), impressions, clicks, conversions, cost, revenue, timestamp,
+			COALESCE(date::text, ''), COALESCE(currency, ''), COALESCE(original_cost, cost), COALESCE(original_revenue, revenue),
+			COALESCE(fx_rate, 0), revision, restated_at
+			FROM campaign_metrics`+where+" ORDER BY timestamp DESC LIMIT 1", args...).Scan(
+		&m.CampaignID,
+		&m.Platform,
+		&m.AccountID,
+		&m.Impressions,
+		&m.Clicks,
+		&m.Conversions,
+		&m.Cost,
+		&m.Revenue,
+		&m.Timestamp,
+		&m.Date,
+		&m.Currency,
+		&m.OriginalCost,
+		&m.OriginalRevenue,
+		&m.FXRate,
+		&m.Revision,
+		&m.RestatedAt,
+	)
+	return m, err
+}